@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// FromArgon2idString converts a hash that was created by CreateHash of the
+// github.com/alexedwards/argon2id package into an Argon2 hash in the native byte layout of this
+// package.
+//
+// The alexedwards/argon2id package stores hashes as PHC strings of the form
+// "$argon2id$v=19$m=65536,t=1,p=2$<salt>$<key>", with the salt and key in standard base64
+// encoding without padding. The string is parsed with ParsePHC. Since that package only supports
+// Argon2id with version 19, any other variant or version is rejected, just like
+// ComparePasswordAndHash of that package would. The converted hash validates with the same
+// password as the original hash, so stored hashes can be migrated without a password reset.
+//
+// Parameters:
+//   - s: The hash string as created by the alexedwards/argon2id package.
+//
+// Returns:
+//   - The Argon2 hash in the native byte layout of this package.
+//   - An error if the string cannot be parsed or uses a variant or version other than Argon2id
+//     with version 19.
+func FromArgon2idString(s string) (Argon2, error) {
+	hash, err := ParsePHC(s)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := hash.Settings()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkArgon2idCompat(settings); err != nil {
+		return nil, fmt.Errorf("failed to convert argon2id string: %w", err)
+	}
+	return hash, nil
+}
+
+// ToArgon2idString converts an Argon2 hash in the native byte layout of this package into the
+// string format of the github.com/alexedwards/argon2id package.
+//
+// The resulting string can be validated with ComparePasswordAndHash of that package, e.g. to
+// keep a rollback path during a migration. Since that package only supports Argon2id with
+// version 19, hashes of any other variant or version cannot be converted.
+//
+// Parameters:
+//   - a: The Argon2 hash to convert.
+//
+// Returns:
+//   - The hash string in the format of the alexedwards/argon2id package.
+//   - An error if the hash is structurally invalid, keyed or uses a variant or version other than
+//     Argon2id with version 19.
+func ToArgon2idString(a Argon2) (string, error) {
+	settings, err := a.Settings()
+	if err != nil {
+		return "", err
+	}
+	if err = checkArgon2idCompat(settings); err != nil {
+		return "", fmt.Errorf("failed to convert Argon2 hash: %w", err)
+	}
+	return a.MarshalPHC()
+}
+
+// checkArgon2idCompat returns an error if the given settings cannot be represented by the
+// alexedwards/argon2id package, which only supports Argon2id with version 19.
+func checkArgon2idCompat(settings Settings) error {
+	if settings.Variant != VariantID {
+		return fmt.Errorf("unsupported Argon2 variant for argon2id: %s", settings.Variant)
+	}
+	if settings.version() != argon2.Version {
+		return fmt.Errorf("%w: %d, only version %d is supported by argon2id", ErrUnsupportedVersion,
+			settings.version(), argon2.Version)
+	}
+	return nil
+}
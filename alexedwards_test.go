@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"testing"
+)
+
+// testArgon2idString was created with CreateHash of github.com/alexedwards/argon2id v1.0.0 for
+// the password "pa$$word" using the params m=64, t=1, p=1 and a salt length of 16.
+const testArgon2idString = "$argon2id$v=19$m=64,t=1,p=1$ljlF/NqGFzXNax9vj0QuKw$" +
+	"6j5JYkuIYRg1nbFv4N9cjfzCeuKSxG7rlWGndjSgBZc"
+
+func TestFromArgon2idString(t *testing.T) {
+	t.Run("convert hash created by argon2id succeeds", func(t *testing.T) {
+		hash, err := FromArgon2idString(testArgon2idString)
+		if err != nil {
+			t.Fatalf("failed to convert argon2id string: %s", err)
+		}
+		if !hash.Validate("pa$$word") {
+			t.Error("converted hash is not valid but should be")
+		}
+		if hash.Validate("password") {
+			t.Error("converted hash should not be valid for a wrong password")
+		}
+	})
+	t.Run("convert invalid string fails", func(t *testing.T) {
+		if _, err := FromArgon2idString("$argon2id$v=19$m=64,t=1,p=1$invalid"); err == nil {
+			t.Error("converting invalid string should have failed")
+		}
+	})
+	t.Run("convert Argon2i string fails", func(t *testing.T) {
+		if _, err := FromArgon2idString(testReferencePHC); err == nil {
+			t.Error("converting Argon2i string should have failed")
+		}
+	})
+	t.Run("convert string without version fails", func(t *testing.T) {
+		_, err := FromArgon2idString("$argon2id$m=64,t=1,p=1$ljlF/NqGFzXNax9vj0QuKw$" +
+			"6j5JYkuIYRg1nbFv4N9cjfzCeuKSxG7rlWGndjSgBZc")
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("converting string without version should fail with unsupported version, got: %v", err)
+		}
+	})
+}
+
+func TestToArgon2idString(t *testing.T) {
+	t.Run("round trip matches the argon2id string", func(t *testing.T) {
+		hash, err := FromArgon2idString(testArgon2idString)
+		if err != nil {
+			t.Fatalf("failed to convert argon2id string: %s", err)
+		}
+		encoded, err := ToArgon2idString(hash)
+		if err != nil {
+			t.Fatalf("failed to convert hash: %s", err)
+		}
+		if encoded != testArgon2idString {
+			t.Errorf("converted hash is not as expected, got: %s, want: %s", encoded, testArgon2idString)
+		}
+	})
+	t.Run("convert Argon2i hash fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Variant = VariantI
+		derived, err := Derive(testPassPhrase, settings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if _, err = ToArgon2idString(derived); err == nil {
+			t.Error("converting Argon2i hash should have failed")
+		}
+	})
+	t.Run("convert invalid hash fails", func(t *testing.T) {
+		if _, err := ToArgon2idString(Argon2{0x01, 0x02}); err == nil {
+			t.Error("converting invalid hash should have failed")
+		}
+	})
+}
@@ -7,24 +7,56 @@
 package argon2
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"runtime"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
 
 // Argon2 represents a slice of bytes used for storing Argon2 password hash or derived key.
+//
+// Since Argon2 is a slice, assigning it to another variable or passing it to a function does
+// not copy the hash, but shares the same backing array. Modifying or wiping one of the values
+// (e.g. via Zero) affects all of them. Use Clone to get an independent copy.
 type Argon2 []byte
 
-// Derive generates an Argon2id hash using the provided password and settings.
+// MaxPasswordLength is the default maximum password length in bytes that is accepted when a hash
+// is derived or validated.
+//
+// Without an upper bound, an attacker could submit a huge password and force the expensive
+// pre-hashing of the Argon2 input, which amplifies a denial of service against password
+// endpoints. Derivations of longer passwords fail with ErrPasswordTooLong. Validations of longer
+// passwords fail as well, but still execute the Argon2 KDF on a truncated password, so that the
+// rejection takes the same amount of time as any other validation. Use WithMaxPasswordLength to
+// configure a different limit for DeriveWithOptions and ValidateWithOptions. DeriveReader and
+// DerivePrehashed derive hashes from key material instead of passwords and are bounded by
+// MaxReaderSecretLength and MaxPrehashedLength instead.
+const MaxPasswordLength = 1024
+
+// noPasswordLimit is the maximum password length of options that disables the length check.
+const noPasswordLimit = -1
+
+// Derive generates an Argon2 hash using the provided password and settings.
+//
+// This function is a convenience wrapper around DeriveBytes for passwords that are
+// available as a string. Since strings are immutable in Go, the password cannot be
+// wiped from memory afterward. If this is a concern, use DeriveBytes instead.
 //
-// This function generates a random salt of the specified length from the provided
-// settings and serializes the settings to create a hash. It then derives an Argon2id
-// key based on the password, salt, and settings, and combines the serialized settings,
-// salt, and derived key into a final hash. The resulting hash is returned along with
-// any errors encountered during the process.
+// The string is treated as an opaque sequence of bytes, so the string-based API is binary-safe
+// and passwords with embedded NUL bytes are hashed in full, exactly like with DeriveBytes. Such
+// passwords may however be truncated by C libraries or database drivers before they reach this
+// package, so binary input like digests is better passed with DerivePrehashed.
 //
 // Parameters:
 //   - password: The password to derive the key from. This should be a string.
@@ -32,77 +64,570 @@ type Argon2 []byte
 //
 // Returns:
 //   - A byte slice containing the concatenated serialized settings, salt, and derived key.
-//   - An error if any issues occur during salt generation or key derivation.
+//   - An error if the settings are invalid, if any issues occur during salt generation or
+//     key derivation, or if the configured Variant or Version is not supported.
 func Derive(password string, settings Settings) (Argon2, error) {
-	salt := make([]byte, settings.SaltLength)
-	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
-		return nil, fmt.Errorf("failed to generate random salt: %w", err)
+	return DeriveBytes([]byte(password), settings)
+}
+
+// MustDerive is like Derive but panics if the hash cannot be derived.
+//
+// It simplifies the initialization of test fixtures and package-level variables, e.g. when
+// seeding test databases, where a failure to generate a salt is genuinely unrecoverable. It
+// must not be used in request-path code, since a misconfiguration or an entropy failure would
+// crash the whole application instead of failing a single request.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+func MustDerive(password string, settings Settings) Argon2 {
+	hash, err := Derive(password, settings)
+	if err != nil {
+		panic(`argon2: Derive: ` + err.Error())
 	}
+	return hash
+}
 
-	serialized := settings.Serialize()
-	hashSize := SerializedSettingsLength + int(settings.SaltLength+settings.KeyLength)
-	hash := make([]byte, hashSize)
-	copy(hash, serialized)
-	copy(hash[SerializedSettingsLength:], salt)
-	key := argon2.IDKey([]byte(password), salt, settings.Time, settings.Memory, settings.Threads,
-		settings.KeyLength)
-	copy(hash[SerializedSettingsLength+int(settings.SaltLength):hashSize], key)
+// DeriveBytes generates an Argon2 hash using the provided password byte slice and settings.
+//
+// This function validates the provided settings, generates a random salt of the specified
+// length from the settings and serializes the settings to create a hash. It then derives an Argon2
+// key of the configured Variant based on the password, salt, and settings, and combines
+// the serialized settings, salt, and derived key into a final hash. The resulting hash
+// is returned along with any errors encountered during the process.
+//
+// The password is not retained or modified, so the caller can safely wipe the byte
+// slice after the function returns.
+//
+// If the random source returns a salt that consists of zeros only, the salt is read once
+// more. If it is still all zeros, the random source is considered broken and an error
+// wrapping ErrZeroSalt is returned.
+//
+// Parameters:
+//   - password: The password to derive the key from as a caller-owned byte slice.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the settings are invalid, if any issues occur during salt generation or
+//     key derivation, or if the configured Variant or Version is not supported.
+func DeriveBytes(password []byte, settings Settings) (Argon2, error) {
+	return derive(password, nil, options{settings: settings}, rand.Reader)
+}
 
-	return hash, nil
+// DeriveWithRand generates an Argon2 hash using the provided password and settings, reading the
+// salt from the provided random source instead of crypto/rand.
+//
+// This allows the use of a hardware RNG or another dedicated entropy source. It also makes it
+// possible to write reproducible tests by providing a deterministic reader. Since the salt is
+// the only random input of the hash generation, a deterministic reader results in a
+// deterministic hash, so a predictable reader must never be used in production.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//   - random: The io.Reader the salt is read from. If nil, crypto/rand.Reader is used.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the settings are invalid, if the salt cannot be read from the random source
+//     or if any issues occur during key derivation.
+func DeriveWithRand(password string, settings Settings, random io.Reader) (Argon2, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+	return derive([]byte(password), nil, options{settings: settings}, random)
+}
+
+// DeriveWithSaltFunc generates an Argon2 hash using the provided password and settings, using
+// the salt that is returned by the provided salt function instead of a random salt.
+//
+// This generalizes DeriveWithRand for advanced salt generation schemes, e.g. a salt that is
+// derived deterministically from a user ID and a secret, so that the same user gets a
+// reproducible, yet unique salt. The salt function is called with the SaltLength of the settings
+// and must return a salt of exactly that length. Like with a random source, a salt that consists
+// of zeros only is rejected with ErrZeroSalt.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//   - saltFn: The function that returns the salt of the requested length. If nil, the salt is
+//     read from crypto/rand.Reader.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the settings are invalid, if the salt function fails or returns a salt of the
+//     wrong length or if any issues occur during key derivation. Errors of the salt function
+//     are wrapped in ErrSaltGeneration.
+func DeriveWithSaltFunc(password string, settings Settings, saltFn func(n uint32) ([]byte, error)) (Argon2, error) {
+	if saltFn == nil {
+		return DeriveWithRand(password, settings, nil)
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	salt, err := saltFn(settings.SaltLength)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSaltGeneration, err)
+	}
+	if len(salt) != int(settings.SaltLength) {
+		return nil, fmt.Errorf("%w: salt function returned %d bytes, expected %d", ErrSaltGeneration,
+			len(salt), settings.SaltLength)
+	}
+	if isZero(salt) {
+		return nil, fmt.Errorf("%w: %w", ErrSaltGeneration, ErrZeroSalt)
+	}
+	return derive([]byte(password), nil, options{settings: settings}, bytes.NewReader(salt))
+}
+
+// DeriveWithSalt generates an Argon2 hash using the provided password, salt and settings.
+//
+// This is the deterministic counterpart to Derive. Instead of generating a random salt, the
+// caller-supplied salt is used and the SaltLength of the settings is set to its length. This
+// allows to reproduce a hash with a known salt, e.g. to verify the migration from another
+// Argon2 library or for compatibility tests. For password storage, use Derive instead, since
+// reusing salts defeats their purpose.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - salt: The salt to use. It must not be empty and at least MinSaltLength bytes long.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the salt is empty, if the settings are invalid or if any issues occur during
+//     the key derivation.
+func DeriveWithSalt(password string, salt []byte, settings Settings) (Argon2, error) {
+	if len(salt) == 0 {
+		return nil, errors.New("salt must not be empty")
+	}
+	settings.SaltLength = uint32(len(salt))
+	o := options{settings: settings, allowZeroSalt: true}
+	return derive([]byte(password), nil, o, bytes.NewReader(salt))
+}
+
+// DeriveContext generates an Argon2 hash using the provided password and settings and aborts
+// waiting for the result once the provided context is cancelled.
+//
+// This allows to stop waiting for an expensive hash derivation, e.g. when the client of an HTTP
+// request has disconnected. If the context is already done before the derivation starts, no
+// derivation takes place.
+//
+// Since the Argon2 KDF of golang.org/x/crypto/argon2 cannot be interrupted, the derivation runs
+// in a separate goroutine. When the context is cancelled, DeriveContext returns immediately, but
+// the goroutine still runs to completion in the background and keeps its CPU and memory
+// resources until then. The result of such an abandoned derivation is discarded.
+//
+// Parameters:
+//   - ctx: The context that controls the cancellation.
+//   - password: The password to derive the key from.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - The error of the context if it is done before the derivation completes, or an error if
+//     any issues occur during the hash generation.
+func DeriveContext(ctx context.Context, password string, settings Settings) (Argon2, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		hash Argon2
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		hash, err := Derive(password, settings)
+		done <- result{hash: hash, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-done:
+		return res.hash, res.err
+	}
+}
+
+// DeriveKey derives a raw key from the provided password and salt using the Argon2 KDF.
+//
+// Unlike Derive, which is meant for password storage, this function is meant for general purpose
+// key derivation, e.g. to derive a symmetric encryption key from a passphrase. The caller
+// controls the salt, which must be stored alongside the encrypted data, and only the derived key
+// is returned, without the serialized settings and the salt.
+//
+// The SaltLength of the settings is ignored, the length of the provided salt is used instead.
+// All other settings, including the Variant and Version, are applied as with Derive.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - salt: The salt for the key derivation. It must be at least MinSaltLength bytes long.
+//   - settings: A Settings struct containing parameters for the Argon2 KDF.
+//
+// Returns:
+//   - A byte slice of settings.KeyLength bytes containing the derived key.
+//   - An error if the settings or the salt length are invalid or if the variant or version is
+//     not supported.
+func DeriveKey(password, salt []byte, settings Settings) ([]byte, error) {
+	settings.SaltLength = uint32(len(salt))
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	if err := checkPasswordLength(password, MaxPasswordLength); err != nil {
+		return nil, err
+	}
+	return deriveKey(password, salt, settings)
+}
+
+// DeriveKeys derives multiple independent raw keys from the provided password and salt using a
+// single invocation of the Argon2 KDF, e.g. an encryption key and a MAC key from one passphrase.
+//
+// The Argon2 KDF is executed once with a key length equal to the sum of the requested key
+// lengths, and its output is split into consecutive segments of the requested lengths. The
+// output of Argon2 is produced by the variable-length hash function H' based on BLAKE2b, so
+// every byte of the output is pseudorandom and no segment reveals anything about the other
+// segments. The keys are therefore cryptographically independent, just like the keys that are
+// split from the output of HKDF-Expand. Note that the keys depend on their order and lengths:
+// requesting different key lengths results in different keys.
+//
+// The SaltLength and KeyLength of the settings are ignored. All other settings are applied as
+// with DeriveKey.
+//
+// Parameters:
+//   - password: The password to derive the keys from.
+//   - salt: The salt for the key derivation. It must be at least MinSaltLength bytes long.
+//   - settings: A Settings struct containing parameters for the Argon2 KDF.
+//   - keyLengths: The lengths of the keys to derive in bytes. At least one key length must be
+//     provided and each of them must be greater than zero.
+//
+// Returns:
+//   - A slice with one key for each of the requested key lengths, in the same order.
+//   - An error if no or a zero key length is requested, if the total length is outside the
+//     allowed range or if the key derivation fails, see DeriveKey.
+func DeriveKeys(password, salt []byte, settings Settings, keyLengths ...uint32) ([][]byte, error) {
+	if len(keyLengths) == 0 {
+		return nil, errors.New("at least one key length must be provided")
+	}
+	var total uint64
+	for _, length := range keyLengths {
+		if length == 0 {
+			return nil, errors.New("key lengths must be greater than zero")
+		}
+		total += uint64(length)
+	}
+	if total > MaxKeyLength {
+		return nil, &InvalidSettingError{Field: "KeyLength", Value: total,
+			Reason: fmt.Sprintf("must be at most %d bytes", MaxKeyLength)}
+	}
+
+	settings.KeyLength = uint32(total)
+	key, err := DeriveKey(password, salt, settings)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([][]byte, len(keyLengths))
+	offset := 0
+	for i, length := range keyLengths {
+		end := offset + int(length)
+		keys[i] = key[offset:end:end]
+		offset = end
+	}
+	return keys, nil
+}
+
+// MaxReaderSecretLength is the maximum number of bytes that DeriveReader reads from the provided
+// io.Reader.
+const MaxReaderSecretLength = 1 << 20
+
+// DeriveReader generates an Argon2 hash from a secret that is read from the provided io.Reader.
+//
+// This function reads the full secret from the reader and derives the hash from it using
+// DeriveBytes. It is useful for hashing file-based credentials or piped input. The data is used
+// verbatim, so a trailing newline is part of the secret. The secret is read with
+// NewSecureBytesFromReader and the read buffer is wiped after the hash has been derived. Since
+// the secret is usually key material, e.g. the content of a key file, it is only limited by
+// MaxReaderSecretLength and not by MaxPasswordLength. Use ValidateReader to validate the hash.
+//
+// Parameters:
+//   - r: The io.Reader to read the secret from. At most MaxReaderSecretLength bytes are accepted.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if reading fails, if the secret exceeds MaxReaderSecretLength or if any issues
+//     occur during the hash generation.
+func DeriveReader(r io.Reader, settings Settings) (Argon2, error) {
+	secret, err := NewSecureBytesFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Wipe()
+	return derive(secret, nil, options{settings: settings, maxPasswordLength: noPasswordLimit}, rand.Reader)
+}
+
+// ValidateReader verifies whether the secret that is read from the provided io.Reader matches an
+// Argon2 hash that was created with DeriveReader.
+//
+// Like DeriveReader, the secret is read with NewSecureBytesFromReader, so at most
+// MaxReaderSecretLength bytes are accepted, and it is not subject to MaxPasswordLength. The read
+// buffer is wiped after the validation. All timing attack mitigations of Validate apply.
+//
+// Parameters:
+//   - r: The io.Reader to read the secret from.
+//
+// Returns:
+//   - true if the secret could be read and matches the stored Argon2 hash.
+func (a Argon2) ValidateReader(r io.Reader) bool {
+	secret, err := NewSecureBytesFromReader(r)
+	if err != nil {
+		return false
+	}
+	defer secret.Wipe()
+	_, err = a.validateWithDummy(secret, nil, false, nil, noPasswordLimit)
+	return err == nil
 }
 
 // Salt extracts and returns the salt from the Argon2 hash.
 //
 // This method retrieves the salt used during the Argon2 key derivation process.
-// If the stored Argon2 hash is shorter than the expected serialized settings length,
-// it returns an empty byte slice.
+// If the stored Argon2 hash does not match the length that is described by its
+// serialized settings, it returns an empty byte slice.
 //
 // Steps performed:
 //   - Copies the Argon2 hash to avoid mutating the original data.
-//   - Checks if the data length is valid; if not, returns an empty slice.
-//   - Extracts the Settings from the serialized portion of the hash.
+//   - Extracts the Settings from the serialized portion of the hash, supporting both
+//     the current and the legacy settings format.
+//   - Checks if the data length is valid; if not, returns an empty slice. The salt and key
+//     lengths of the settings are read from untrusted data, so they are checked against the
+//     actual length of the data before slicing, and a tampered header never causes a panic.
 //   - Returns the salt portion of the hash based on the extracted settings.
 //
 // Returns:
-//   - A byte slice containing the salt extracted from the Argon2 hash.
+//   - A byte slice containing the salt extracted from the Argon2 hash. The returned slice is
+//     a copy, so modifying or wiping the Argon2 hash (e.g. via Zero) does not affect it.
 //   - If the stored data is invalid or too short, an empty slice is returned.
 func (a Argon2) Salt() []byte {
 	data := make([]byte, len(a))
 	copy(data, a)
 
-	if len(data) < SerializedSettingsLength {
+	layout, ok := parseLayout(data)
+	if !ok {
 		return []byte{}
 	}
-
-	settings := SettingsFromBytes(data[:SerializedSettingsLength])
-	return data[SerializedSettingsLength : SerializedSettingsLength+int(settings.SaltLength)]
+	return layout.salt(data)
 }
 
 // Key extracts and returns the derived key from the Argon2 hash.
 //
 // This method retrieves the key that was generated during the Argon2 key derivation process.
-// If the stored Argon2 hash is shorter than the expected serialized settings length,
-// it returns an empty byte slice.
+// If the stored Argon2 hash does not match the length that is described by its
+// serialized settings, it returns an empty byte slice.
 //
 // Steps performed:
 //   - Copies the Argon2 hash to avoid modifying the original data.
-//   - Checks if the data length is valid; if not, returns an empty slice.
-//   - Extracts the Settings from the serialized portion of the hash.
+//   - Extracts the Settings from the serialized portion of the hash, supporting both
+//     the current and the legacy settings format.
+//   - Checks if the data length is valid; if not, returns an empty slice. The salt and key
+//     lengths of the settings are read from untrusted data, so they are checked against the
+//     actual length of the data before slicing, and a tampered header never causes a panic.
 //   - Returns the derived key portion of the hash based on the extracted settings.
 //
 // Returns:
-//   - A byte slice containing the derived key extracted from the Argon2 hash.
+//   - A byte slice containing the derived key extracted from the Argon2 hash. The returned slice
+//     is a copy, so modifying or wiping the Argon2 hash (e.g. via Zero) does not affect it.
 //   - If the stored data is invalid or too short, an empty slice is returned.
 func (a Argon2) Key() []byte {
 	data := make([]byte, len(a))
 	copy(data, a)
 
-	if len(data) < SerializedSettingsLength {
+	layout, ok := parseLayout(data)
+	if !ok {
 		return []byte{}
 	}
+	return layout.key(data)
+}
 
-	settings := SettingsFromBytes(data[:SerializedSettingsLength])
-	return data[SerializedSettingsLength+int(settings.SaltLength) : SerializedSettingsLength+int(settings.SaltLength+settings.KeyLength)]
+// SaltE extracts and returns the salt from the Argon2 hash, like Salt, but returns an error if
+// the hash is structurally invalid.
+//
+// Unlike Salt, which returns an empty slice for invalid hashes, this method makes a malformed
+// hash visible, e.g. a hash that is too short or whose serialized SaltLength exceeds the
+// available data.
+//
+// Returns:
+//   - A copy of the salt of the Argon2 hash.
+//   - An error wrapping ErrInvalidHashLength if the hash is too short or does not match the
+//     length that is described by its serialized settings.
+func (a Argon2) SaltE() ([]byte, error) {
+	layout, err := a.checkedLayout()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract salt: %w", err)
+	}
+	return bytes.Clone(layout.salt(a)), nil
+}
+
+// KeyE extracts and returns the derived key from the Argon2 hash, like Key, but returns an error
+// if the hash is structurally invalid.
+//
+// Unlike Key, which returns an empty slice for invalid hashes, this method makes a malformed
+// hash visible, e.g. a hash that is too short or whose serialized KeyLength exceeds the
+// available data.
+//
+// Returns:
+//   - A copy of the derived key of the Argon2 hash.
+//   - An error wrapping ErrInvalidHashLength if the hash is too short or does not match the
+//     length that is described by its serialized settings.
+func (a Argon2) KeyE() ([]byte, error) {
+	layout, err := a.checkedLayout()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract key: %w", err)
+	}
+	return bytes.Clone(layout.key(a)), nil
+}
+
+// Equal reports whether the Argon2 hash is byte-for-byte equal to the other Argon2 hash.
+//
+// The hashes are compared using subtle.ConstantTimeCompare over the whole slice, so the time
+// taken does not depend on the position of the first differing byte, e.g. when detecting
+// duplicate stored credentials. Hashes of different lengths are never equal. Note that two
+// hashes of the same password are not equal, since they use different random salts, and that
+// a hash in an older format is not equal to the same hash after UpgradeHash.
+//
+// Parameters:
+//   - other: The Argon2 hash to compare with.
+//
+// Returns:
+//   - true if both hashes have the same length and content, false otherwise.
+func (a Argon2) Equal(other Argon2) bool {
+	return subtle.ConstantTimeCompare(a, other) == 1
+}
+
+// SaltEqual reports whether the Argon2 hash uses the same salt as the other Argon2 hash.
+//
+// Since salts are generated randomly, two hashes sharing the same salt are a strong sign of a
+// broken random number generator. This method allows to detect such a salt reuse, e.g. when
+// auditing a database. The salts are compared using subtle.ConstantTimeCompare, to keep the API
+// consistent with the rest of the package.
+//
+// Parameters:
+//   - other: The Argon2 hash to compare the salt with.
+//
+// Returns:
+//   - true if both hashes are structurally valid and use the same salt, false otherwise.
+func (a Argon2) SaltEqual(other Argon2) bool {
+	layout, ok := parseLayout(a)
+	if !ok {
+		return false
+	}
+	otherLayout, ok := parseLayout(other)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(layout.salt(a), otherLayout.salt(other)) == 1
+}
+
+// CreatedAt returns the creation timestamp that is stored in the Argon2 hash.
+//
+// Only hashes that were derived using the WithTimestamp option carry a creation timestamp.
+// The timestamp has a resolution of seconds. It is not part of the PHC representation of the hash
+// and is not authenticated by the Argon2 KDF, so it must not be relied on for
+// security decisions if the stored hash can be modified by an attacker.
+//
+// Returns:
+//   - The creation time of the hash.
+//   - true if the hash is structurally valid and carries a creation timestamp, false otherwise.
+func (a Argon2) CreatedAt() (time.Time, bool) {
+	layout, ok := parseLayout(a)
+	if !ok || !layout.settings.Timestamped {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(binary.LittleEndian.Uint64(layout.timestamp(a))), 0), true
+}
+
+// Settings extracts and returns the Settings that are embedded in the Argon2 hash.
+//
+// This method deserializes the settings header of the hash, supporting both the current and the
+// legacy settings format. It allows to inspect the parameters of a stored hash, e.g. to compare
+// them against the current configuration, without having to deal with the serialized layout.
+//
+// Returns:
+//   - The Settings that were used to derive the Argon2 hash. For legacy hashes, the Version is
+//     set to the current Argon2 version.
+//   - An error wrapping ErrInvalidHashLength if the hash is too short or does not match the
+//     length that is described by its serialized settings.
+func (a Argon2) Settings() (Settings, error) {
+	layout, err := a.checkedLayout()
+	if err != nil {
+		return Settings{}, err
+	}
+	return layout.settings, nil
+}
+
+// IsValid reports whether the Argon2 hash is structurally valid.
+//
+// This method checks the hash without requiring the password, e.g. to filter a bulk import
+// before the hashes are stored. It applies the same length checks as Scan and additionally
+// requires the embedded settings to be within the ranges that are accepted by Settings.Validate.
+// It does not execute the Argon2 KDF.
+//
+// Returns:
+//   - true if the length of the hash matches the salt and key lengths of its serialized settings
+//     and the settings are valid, false otherwise.
+func (a Argon2) IsValid() bool {
+	layout, ok := parseLayout(a)
+	return ok && layout.settings.Validate() == nil
+}
+
+// Hex returns the lowercase hex encoding of the full Argon2 hash, including the serialized
+// settings, the salt and the derived key.
+//
+// Like String, the returned value contains the full key material and should be treated as
+// sensitive.
+func (a Argon2) Hex() string {
+	return hex.EncodeToString(a)
+}
+
+// Base64URL returns the unpadded URL-safe base64 encoding of the full Argon2 hash, including the
+// serialized settings, the salt and the derived key.
+//
+// The result is a compact, self-describing token that can be embedded in URLs or JWT claims and
+// is converted back with ParseBase64URL. Like String, the returned value contains the full key
+// material and should be treated as sensitive.
+func (a Argon2) Base64URL() string {
+	return base64.RawURLEncoding.EncodeToString(a)
+}
+
+// ParseBase64URL decodes an Argon2 hash from its unpadded URL-safe base64 encoding as returned
+// by Base64URL.
+//
+// The decoded hash is checked structurally like with IsValid, so that a corrupted or truncated
+// token is rejected before it is stored or validated.
+//
+// Parameters:
+//   - s: The unpadded URL-safe base64 encoded Argon2 hash.
+//
+// Returns:
+//   - The Argon2 hash in the native byte layout.
+//   - An error if the string is not valid base64, wrapping ErrInvalidHashLength if the decoded
+//     hash is structurally invalid, or an error if its settings are outside the allowed ranges.
+func ParseBase64URL(s string) (Argon2, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Argon2 hash: %w", err)
+	}
+	hash := Argon2(data)
+	layout, err := hash.checkedLayout()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Argon2 hash: %w", err)
+	}
+	if err = layout.settings.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to decode Argon2 hash: %w", err)
+	}
+	return hash, nil
 }
 
 // Validate verifies whether the given password matches the Argon2 hash.
@@ -113,14 +638,18 @@ func (a Argon2) Key() []byte {
 // timing attacks.
 //
 // Steps performed:
-//   - Copies the Argon2 hash data to prevent mutation.
-//   - If the input data is too short or empty, it falls back to `DefaultSettings` and
-//     generates a random salt and key.
+//   - Reads the Argon2 hash data in place without modifying it.
+//   - If the input data is too short or empty, or if the stored settings are outside the
+//     allowed ranges, it falls back to `DefaultSettings` and generates a random salt and key.
 //   - If the stored hash does not match the expected structure (e.g., incorrect key length),
 //     it regenerates random values to avoid leaking information about tampered or invalid hashes.
 //   - Computes the Argon2 key from the provided password using the extracted settings and salt.
+//     The variant stored in the hash decides which KDF is used. If the variant or the Argon2
+//     version is not supported, the Argon2id KDF is executed anyway and the validation fails.
 //   - Compares the derived key with the stored key using subtle.ConstantTimeCompare.
 //
+// Like Derive, Validate is binary-safe and compares passwords with embedded NUL bytes in full.
+//
 // Parameters:
 //   - password: The plaintext password to validate against the Argon2 hash.
 //
@@ -132,32 +661,630 @@ func (a Argon2) Key() []byte {
 //     prevent timing attacks that could hint at the validity of stored data.
 //   - Uses constant-time comparison to mitigate side-channel attacks.
 func (a Argon2) Validate(password string) bool {
-	data := make([]byte, len(a))
-	copy(data, a)
+	return a.ValidateBytes([]byte(password))
+}
 
-	// If an invalid length or zero byte slice is passed, we fall back to the DefaultSettings.
-	// This is crucial, so that we do not skip the CPU and memory consuption of the KDF and
-	// potentially run into a timing attack.
-	if len(data) < SerializedSettingsLength {
-		data = make([]byte, SerializedSettingsLength+int(DefaultSettings.SaltLength+DefaultSettings.KeyLength))
-		copy(data, DefaultSettings.Serialize())
-		_, _ = io.ReadFull(rand.Reader, data[SerializedSettingsLength:])
+// DummyValidate executes a full Argon2 validation against a random dummy hash and always
+// returns false.
+//
+// It is meant to be called in code paths where no stored hash is available, e.g. when a login
+// is attempted for a username that does not exist. Skipping the expensive KDF in such a case
+// makes the response noticeably faster and allows an attacker to enumerate existing accounts.
+// Calling DummyValidate instead takes the same amount of time as validating a real hash.
+//
+// The dummy hash is derived with DefaultSettings. The timing only matches the validation of
+// real hashes if they were derived with the same settings. If the stored hashes use different
+// settings, derive a dummy hash with those settings once at startup and validate the password
+// against it instead.
+//
+// Parameters:
+//   - password: The plaintext password that was provided with the login attempt.
+//
+// Returns:
+//   - Always false.
+func DummyValidate(password string) bool {
+	_, _ = Argon2(nil).validate([]byte(password), nil)
+	return false
+}
+
+// CheckCredential verifies whether the given password matches the stored hash of a user, with
+// the same timing regardless of whether the user exists.
+//
+// This is the building block for timing-safe authentication handlers: look up the stored hash
+// of the user and pass it to CheckCredential, or pass nil if the user does not exist. For a
+// nil, empty or structurally invalid hash, a full Argon2 KDF with DefaultSettings is executed
+// anyway and false is returned, so the response time does not reveal whether the account
+// exists:
+//
+//	var hash argon2.Argon2
+//	if user, found := users[username]; found {
+//		hash = user.PasswordHash
+//	}
+//	if !argon2.CheckCredential(hash, password) {
+//		return errInvalidLogin
+//	}
+//
+// As with DummyValidate, the timing only matches if the stored hashes were derived with
+// DefaultSettings.
+//
+// Parameters:
+//   - storedHash: The stored Argon2 hash of the user, or nil if the user does not exist.
+//   - password: The plaintext password that was provided with the login attempt.
+//
+// Returns:
+//   - true if the stored hash is valid and matches the password, false otherwise.
+func CheckCredential(storedHash Argon2, password string) bool {
+	_, err := storedHash.validate([]byte(password), nil)
+	return err == nil
+}
+
+// ValidateAny verifies whether the given password matches any of the given Argon2 hashes.
+//
+// This supports grace periods during credential migrations, where a user might have an old and
+// a new hash. Every candidate is validated, even after a match has been found, so that the
+// timing does not reveal which of the hashes matched. If no hashes are given, a dummy
+// validation with DefaultSettings is executed, like with DummyValidate.
+//
+// Parameters:
+//   - password: The plaintext password to validate.
+//   - hashes: The Argon2 hashes to validate the password against.
+//
+// Returns:
+//   - true if the password matches at least one of the hashes, false otherwise.
+func ValidateAny(password string, hashes ...Argon2) bool {
+	if len(hashes) == 0 {
+		return DummyValidate(password)
+	}
+	matched := 0
+	for _, hash := range hashes {
+		_, err := hash.validate([]byte(password), nil)
+		matched |= subtle.ConstantTimeEq(boolToInt32(err == nil), 1)
 	}
+	return matched == 1
+}
+
+// boolToInt32 converts the given boolean to 1 if true and 0 if false.
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
 
+// ValidateBytes verifies whether the given password byte slice matches the Argon2 hash.
+//
+// This method behaves exactly like Validate, including all timing attack mitigations, but
+// operates directly on a caller-owned byte slice. The password is not retained or modified,
+// so the caller can safely wipe the byte slice after the method returns.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//
+// Returns:
+//   - true if the password is valid and matches the stored Argon2 hash.
+func (a Argon2) ValidateBytes(password []byte) bool {
+	_, err := a.validate(password, nil)
+	return err == nil
+}
+
+// VerifyPassword verifies whether the given password matches the Argon2 hash and returns an
+// error that describes why the verification failed.
+//
+// Unlike Validate, this method allows to distinguish a wrong password from a corrupted or
+// tampered hash, e.g. for audit logging. All timing attack mitigations of Validate are kept
+// intact: the Argon2 KDF is always executed, even if the hash is structurally invalid, so the
+// type of the returned error does not leak structural information via timing.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//
+// Returns:
+//   - nil if the password is valid and matches the stored Argon2 hash.
+//   - ErrMismatchedHashAndPassword if the hash is valid but does not match the password.
+//   - ErrPasswordTooLong if the password exceeds MaxPasswordLength.
+//   - An error wrapping ErrInvalidHash if the hash is structurally invalid or cannot be validated.
+//     If the hash uses an Argon2 version other than 0x13, which is the only version that is
+//     implemented by golang.org/x/crypto/argon2, the error also wraps ErrUnsupportedVersion.
+func (a Argon2) VerifyPassword(password string) error {
+	_, err := a.validate([]byte(password), nil)
+	return err
+}
+
+// ValidateAndCheck verifies whether the given password matches the Argon2 hash and reports
+// whether the hash should be re-derived with the given target settings.
+//
+// This method combines Validate and NeedsRehash, so that the hot login path only deserializes
+// the stored settings once and executes the Argon2 KDF exactly once. The rehash check is only
+// performed after the password was successfully validated. The same timing attack mitigations
+// as in Validate apply.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//   - target: The Settings that the stored hash should at least comply with.
+//
+// Returns:
+//   - valid: true if the password is valid and matches the stored Argon2 hash.
+//   - needsRehash: true if the password is valid and the stored hash was derived with weaker
+//     parameters than the target settings. It is always false if the password is not valid.
+func (a Argon2) ValidateAndCheck(password string, target Settings) (valid bool, needsRehash bool) {
+	layout, err := a.validate([]byte(password), nil)
+	if err != nil {
+		return false, false
+	}
+	return true, layout.settings.weakerThan(target)
+}
+
+// validate implements the validation logic of Validate and returns the layout that was used
+// for the validation alongside with the result. If associatedData is not empty, it is bound
+// to the stored salt before the key is derived.
+//
+// The returned error is nil if the password matches the hash, ErrMismatchedHashAndPassword if
+// it does not match and wraps ErrInvalidHash if the hash is structurally invalid or cannot be
+// validated. The Argon2 KDF is executed in all cases.
+func (a Argon2) validate(password, associatedData []byte) (hashLayout, error) {
+	return a.validateWithDummy(password, associatedData, false, nil, MaxPasswordLength)
+}
+
+// validateWithDummy implements validate. If the hash is structurally invalid and its stored
+// settings are outside the allowed ranges, the given dummy hash is validated instead. If dummy
+// is nil, a dummy hash with DefaultSettings and a random salt and key is generated. The dummy
+// hash is only read, so it can be shared between concurrent validations. The password only
+// matches if the keyed flag of the stored settings equals keyed, so that keyed and unkeyed
+// hashes can never be validated in place of each other. Passwords that exceed maxPasswordLength
+// fail with ErrPasswordTooLong; a maxPasswordLength of zero or less disables the check.
+func (a Argon2) validateWithDummy(password, associatedData []byte, keyed bool, dummy Argon2,
+	maxPasswordLength int,
+) (hashLayout, error) {
+	// The hash is only read during the validation, so a valid hash is used in place without
+	// copying it first.
+	data := []byte(a)
+
+	// Passwords that exceed the maximum length are truncated, so that the Argon2 KDF is still
+	// executed at the usual cost, but the validation always fails.
+	password, tooLong := truncatePassword(password, maxPasswordLength)
+
+	// If the serialized settings are outside the allowed ranges, the hash was not created
+	// by Derive and the data is either corrupted or tampered with. Executing the Argon2 KDF
+	// with such settings could panic, so we handle it like any other invalid hash.
+	layout, ok := parseLayout(data)
+	if ok && layout.settings.Validate() != nil {
+		ok = false
+	}
+
+	// If an invalid length or zero byte slice is passed, we fall back to the dummy hash or to
+	// the DefaultSettings.
+	// This is crucial, so that we do not skip the CPU and memory consuption of the KDF and
+	// potentially run into a timing attack.
+	//
 	// If the byte slice does not provide the expected key length we can assume that the data
 	// is either corrupted or tampered with. In this case we also have potential for a timing
-	// attack and apply the same logic as with empty data and always execute the Argon2 KDF.
-	settings := SettingsFromBytes(data[:SerializedSettingsLength])
-	if len(data) != SerializedSettingsLength+int(settings.SaltLength+settings.KeyLength) {
-		data = make([]byte, SerializedSettingsLength+int(settings.SaltLength+settings.KeyLength))
-		copy(data, data[:SerializedSettingsLength])
-		_, _ = io.ReadFull(rand.Reader, data[SerializedSettingsLength:])
+	// attack and apply the same logic as with empty data and always execute the Argon2 KDF,
+	// using the settings of the stored hash if they are within the allowed ranges.
+	if !ok {
+		var stored Settings
+		if len(data) >= LegacySerializedSettingsLength {
+			stored = settingsFromHeader(data)
+		}
+		switch {
+		case stored.Validate() != nil && dummy != nil:
+			layout, _ = parseLayout(dummy)
+			data = dummy
+		default:
+			settings := DefaultSettings
+			if stored.Validate() == nil {
+				settings = stored
+			}
+			layout = hashLayout{settings: settings, headerLength: SerializedSettingsLength}
+			buf := getDummyBuffer(layout.length())
+			defer dummyBufferPool.Put(buf)
+			data = *buf
+			settings.serializeTo(data)
+			_, _ = io.ReadFull(rand.Reader, data[SerializedSettingsLength:])
+		}
+	}
+
+	settings := layout.settings
+	salt := bindAssociatedData(layout.salt(data), associatedData)
+	key := layout.key(data)
+	derived, err := deriveKey(password, salt, settings)
+	if err != nil {
+		// We still execute the Argon2 KDF for unsupported variants or versions so that the
+		// validation takes the same amount of time as for any other stored hash.
+		settings.Variant = VariantID
+		settings.Version = argon2.Version
+		_, _ = deriveKey(password, salt, settings)
+		return layout, fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+
+	match := subtle.ConstantTimeCompare(key, derived) == 1 && settings.Keyed == keyed
+	switch {
+	case !ok:
+		return layout, ErrInvalidHash
+	case tooLong:
+		return layout, ErrPasswordTooLong
+	case !match:
+		return layout, ErrMismatchedHashAndPassword
+	default:
+		return layout, nil
+	}
+}
+
+// dummyBufferPool holds the buffers for the dummy hashes that validate uses as a fallback for
+// invalid hashes, to reduce the allocations when validating many invalid hashes.
+var dummyBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, DefaultSettings.HashLength())
+		return &buf
+	},
+}
+
+// getDummyBuffer returns a buffer of the given length from the dummyBufferPool. The buffer must
+// be returned to the pool once it is no longer needed.
+func getDummyBuffer(length int) *[]byte {
+	buf := dummyBufferPool.Get().(*[]byte)
+	if cap(*buf) < length {
+		*buf = make([]byte, length)
+	}
+	*buf = (*buf)[:length]
+	return buf
+}
+
+// NeedsRehash reports whether the Argon2 hash was derived with weaker parameters than the
+// given target settings.
+//
+// This method is meant to be called right after a successful validation of a password. If
+// it returns true, the password should be re-derived with the target settings and the stored
+// hash should be replaced, so that stored hashes are upgraded transparently over time.
+//
+// Steps performed:
+//   - Extracts the Settings from the serialized portion of the hash.
+//   - If the hash is structurally invalid, a rehash is always required.
+//   - Compares the Memory, Time, Threads, SaltLength and KeyLength of the stored settings
+//     with the target settings. If any of them is lower than the target, a rehash is required.
+//   - If the stored Variant differs from the target Variant, a rehash is required as well.
+//
+// Parameters:
+//   - target: The Settings that the stored hash should at least comply with.
+//
+// Returns:
+//   - true if the hash should be re-derived with the target settings, false otherwise.
+func (a Argon2) NeedsRehash(target Settings) bool {
+	layout, ok := parseLayout(a)
+	if !ok {
+		return true
+	}
+
+	return layout.settings.weakerThan(target)
+}
+
+// UsesDefaults reports whether the Argon2 hash was derived with the current DefaultSettings.
+//
+// Since DefaultSettings may change between releases, use UsesSettings to compare against a
+// pinned reference, e.g. in a security audit that reports how many hashes still use the
+// parameters of an older release.
+//
+// Returns:
+//   - true if the hash is structurally valid and its settings match DefaultSettings.
+func (a Argon2) UsesDefaults() bool {
+	return a.UsesSettings(DefaultSettings)
+}
+
+// UsesSettings reports whether the Argon2 hash was derived with the given Settings.
+//
+// Unlike NeedsRehash, which only checks whether the stored settings are weaker than the target,
+// all parameters have to match exactly, as with Settings.Equal. The Keyed and Timestamped flags
+// are not considered, since they are set by the package and are not part of the Settings that
+// are passed to the Derive functions.
+//
+// Parameters:
+//   - s: The Settings to compare the stored settings against.
+//
+// Returns:
+//   - true if the hash is structurally valid and its settings match the given Settings.
+func (a Argon2) UsesSettings(s Settings) bool {
+	layout, ok := parseLayout(a)
+	if !ok {
+		return false
 	}
+	stored := layout.settings
+	stored.Keyed, stored.Timestamped = false, false
+	s.Keyed, s.Timestamped = false, false
+	return stored.Equal(s)
+}
+
+// Rehash derives a new Argon2 hash from the password using the new settings.
+//
+// This method completes the password upgrade workflow of a login handler:
+//
+//	if !hash.Validate(password) {
+//		return errInvalidLogin
+//	}
+//	if hash.NeedsRehash(argon2.DefaultSettings) {
+//		upgraded, err := hash.Rehash(password, argon2.DefaultSettings)
+//		// store upgraded instead of hash
+//	}
+//
+// Rehash does not validate the password against the existing hash, so it must only be called
+// after a successful validation. The existing hash is not modified.
+//
+// Parameters:
+//   - password: The plaintext password that was successfully validated against the hash.
+//   - newSettings: The Settings to derive the new hash with.
+//
+// Returns:
+//   - The newly derived Argon2 hash.
+//   - An error if the new settings are invalid or if any issues occur during the hash generation.
+func (a Argon2) Rehash(password string, newSettings Settings) (Argon2, error) {
+	return Derive(password, newSettings)
+}
 
-	salt := data[SerializedSettingsLength : SerializedSettingsLength+int(settings.SaltLength)]
-	key := data[SerializedSettingsLength+int(settings.SaltLength) : SerializedSettingsLength+int(settings.SaltLength+settings.KeyLength)]
-	derived := argon2.IDKey([]byte(password), salt, settings.Time, settings.Memory, settings.Threads,
-		settings.KeyLength)
+// UpgradeHash rewrites an Argon2 hash in an older serialized format into the current format.
+//
+// Hashes in format version 0, which were written before the format-version byte was introduced,
+// as well as legacy hashes without the Argon2 version byte are migrated by re-serializing their
+// settings with the current layout. The salt and key are copied unchanged, so no password is
+// required and the upgraded hash validates exactly like the original one. Hashes that are
+// already in the current format are returned as an independent copy.
+//
+// Parameters:
+//   - old: The Argon2 hash to upgrade.
+//
+// Returns:
+//   - A new Argon2 hash in the current serialized format.
+//   - An error wrapping ErrInvalidHashLength if the hash is structurally invalid.
+func UpgradeHash(old Argon2) (Argon2, error) {
+	layout, ok := parseLayout(old)
+	if !ok {
+		return nil, fmt.Errorf("failed to upgrade Argon2 hash: %w", ErrInvalidHashLength)
+	}
+	if layout.headerLength == SerializedSettingsLength {
+		return old.Clone(), nil
+	}
+	return newHash(layout.settings, layout.salt(old), layout.key(old)), nil
+}
 
-	return subtle.ConstantTimeCompare(key, derived) == 1
+// Zero overwrites the Argon2 hash with zeros to wipe the key material from memory.
+//
+// This method overwrites the entire backing array of the Argon2 hash, including any spare
+// capacity, with zeros. It is meant to be used for long-lived Argon2 values, e.g. in a
+// credential cache, that should be scrubbed when they are no longer needed. After calling
+// Zero, the Argon2 hash is no longer valid.
+//
+// Since Argon2 is a byte slice, all values that share the same backing array are wiped as
+// well. Slices returned by Salt and Key are copies and are not affected, just like values
+// that were created with Clone.
+//
+// Security considerations:
+//   - The zeroing loop is followed by runtime.KeepAlive, so that the compiler cannot treat
+//     the writes as dead stores and optimize them away.
+//   - Copies of the data that were created before, e.g. by the garbage collector moving
+//     memory or by explicit copies in the application, cannot be wiped by this method.
+func (a Argon2) Zero() {
+	data := a[:cap(a)]
+	for i := range data {
+		data[i] = 0
+	}
+	runtime.KeepAlive(data)
+}
+
+// Clone returns an independent copy of the Argon2 hash.
+//
+// The returned hash does not share its backing array with the original hash, so modifying or
+// wiping one of them (e.g. via Zero) does not affect the other. A nil hash is cloned to nil.
+//
+// Returns:
+//   - A copy of the Argon2 hash.
+func (a Argon2) Clone() Argon2 {
+	return bytes.Clone(a)
+}
+
+// derive implements the hash generation of DeriveBytes using the settings of the given options.
+// The salt is read from the given random source. If associatedData is not empty, it is bound to
+// the random salt before the key is derived. Only the random salt is stored in the hash.
+func derive(password, associatedData []byte, o options, random io.Reader) (Argon2, error) {
+	password = o.password(password)
+	if o.rejectEmpty && len(password) == 0 {
+		return nil, ErrEmptyPassword
+	}
+	if err := checkPasswordLength(password, o.maxPassword()); err != nil {
+		return nil, err
+	}
+	settings := o.settings
+	settings.Keyed = o.keyed
+	settings.Timestamped = o.timestamp
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	salt, err := generateSalt(random, settings.SaltLength, o.allowZeroSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := deriveKey(password, bindAssociatedData(salt, associatedData), settings)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := newHash(settings, salt, key)
+	if settings.Timestamped {
+		binary.LittleEndian.PutUint64(hash[len(hash)-TimestampLength:], uint64(o.created().Unix()))
+	}
+	return hash, nil
+}
+
+// passwordTooLong reports whether the given password exceeds the given maximum length. A maximum
+// length of zero or less disables the check.
+func passwordTooLong(password []byte, maxLength int) bool {
+	return maxLength > 0 && len(password) > maxLength
+}
+
+// checkPasswordLength returns an error wrapping ErrPasswordTooLong if the given password exceeds
+// the given maximum length. Derivations that transform the password, e.g. by peppering it, check
+// the password before the transformation, so that the limit applies to the input of the caller.
+func checkPasswordLength(password []byte, maxLength int) error {
+	if passwordTooLong(password, maxLength) {
+		return fmt.Errorf("%w of %d bytes", ErrPasswordTooLong, maxLength)
+	}
+	return nil
+}
+
+// truncatePassword truncates the given password to the given maximum length and reports whether
+// it was too long. Validations use it so that the Argon2 KDF is still executed at the usual cost
+// for a too long password, while the validation always fails.
+func truncatePassword(password []byte, maxLength int) ([]byte, bool) {
+	if !passwordTooLong(password, maxLength) {
+		return password, false
+	}
+	return password[:maxLength], true
+}
+
+// generateSalt reads a salt of the given length from the given random source.
+//
+// Unless allowZero is set, a salt that consists of zeros only is treated as a sign of a broken
+// random source. In this case the salt is read once more before ErrZeroSalt is returned, since a
+// legitimate all-zero salt is astronomically unlikely.
+func generateSalt(random io.Reader, length uint32, allowZero bool) ([]byte, error) {
+	salt := make([]byte, length)
+	for attempt := 0; ; attempt++ {
+		if _, err := io.ReadFull(random, salt); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSaltGeneration, err)
+		}
+		if allowZero || !isZero(salt) {
+			return salt, nil
+		}
+		if attempt > 0 {
+			return nil, fmt.Errorf("%w: %w", ErrSaltGeneration, ErrZeroSalt)
+		}
+	}
+}
+
+// isZero reports whether the given byte slice consists of zeros only.
+func isZero(p []byte) bool {
+	var acc byte
+	for _, b := range p {
+		acc |= b
+	}
+	return acc == 0
+}
+
+// deriveKey derives the raw Argon2 key for the given password and salt using the KDF that
+// matches the Variant of the provided settings. It returns an error if the Variant or the
+// Version is not supported by golang.org/x/crypto/argon2.
+func deriveKey(password, salt []byte, settings Settings) ([]byte, error) {
+	if settings.version() != argon2.Version {
+		return nil, fmt.Errorf("%w: %d, only version %d is supported", ErrUnsupportedVersion,
+			settings.version(), argon2.Version)
+	}
+
+	switch settings.Variant {
+	case VariantID:
+		return argon2.IDKey(password, salt, settings.Time, settings.Memory, settings.Threads,
+			settings.KeyLength), nil
+	case VariantI:
+		return argon2.Key(password, salt, settings.Time, settings.Memory, settings.Threads,
+			settings.KeyLength), nil
+	default:
+		return nil, settings.validateVariant()
+	}
+}
+
+// newHash assembles an Argon2 hash in the current format from the given settings, salt and key.
+// The SaltLength and KeyLength of the settings must match the lengths of salt and key.
+func newHash(settings Settings, salt, key []byte) Argon2 {
+	hashSize := SerializedSettingsLength + len(salt) + len(key)
+	if settings.Timestamped {
+		hashSize += TimestampLength
+	}
+	hash := make([]byte, hashSize)
+	copy(hash, settings.Serialize())
+	copy(hash[SerializedSettingsLength:], salt)
+	copy(hash[SerializedSettingsLength+len(salt):], key)
+	return hash
+}
+
+// hashLayout describes the structure of a serialized Argon2 hash.
+type hashLayout struct {
+	settings     Settings
+	headerLength int
+}
+
+// parseLayout reads the serialized settings from the given hash data and determines the layout
+// of the hash. Hashes in the current format, format version 0 hashes without the format-version
+// byte as well as legacy hashes without the version byte are supported. Current hashes are
+// detected by the leading format-version byte, the older formats are told apart by the total
+// length of the data. In any case, the length must match the salt and key lengths stored in the
+// settings. It returns false if the data does not match any of the formats.
+//
+// The salt and key lengths are read from untrusted data, so the lengths are summed up as uint64.
+// This prevents an overflow on platforms with a 32 bit int, which would otherwise allow crafted
+// lengths to match a short hash and to make Salt and Key slice out of bounds.
+func parseLayout(data []byte) (hashLayout, bool) {
+	if len(data) < LegacySerializedSettingsLength {
+		return hashLayout{}, false
+	}
+
+	if len(data) >= SerializedSettingsLength && data[0] == SerializedFormatVersion {
+		settings := settingsFromBytes(data[1:SerializedSettingsLength])
+		if uint64(len(data)) == SerializedSettingsLength+settings.payloadLength() {
+			return hashLayout{settings: settings, headerLength: SerializedSettingsLength}, true
+		}
+	}
+
+	settings := settingsFromBytes(data[:LegacySerializedSettingsLength])
+	if settings.Timestamped {
+		// The older formats were never written with a creation timestamp.
+		return hashLayout{}, false
+	}
+	payload := settings.payloadLength()
+	switch uint64(len(data)) {
+	case UnversionedSerializedSettingsLength + payload:
+		settings.Version = data[UnversionedSerializedSettingsLength-1]
+		return hashLayout{settings: settings, headerLength: UnversionedSerializedSettingsLength}, true
+	case LegacySerializedSettingsLength + payload:
+		return hashLayout{settings: settings, headerLength: LegacySerializedSettingsLength}, true
+	default:
+		return hashLayout{}, false
+	}
+}
+
+// checkedLayout determines the layout of the Argon2 hash like parseLayout, but returns a
+// descriptive error wrapping ErrInvalidHashLength if the hash is structurally invalid.
+func (a Argon2) checkedLayout() (hashLayout, error) {
+	if len(a) < LegacySerializedSettingsLength {
+		return hashLayout{}, fmt.Errorf("%w, got: %d, expected at least: %d", ErrInvalidHashLength, len(a),
+			LegacySerializedSettingsLength)
+	}
+	layout, ok := parseLayout(a)
+	if !ok {
+		settings := settingsFromHeader(a)
+		return hashLayout{}, fmt.Errorf("%w, got: %d, expected: %d", ErrInvalidHashLength, len(a),
+			settings.HashLength())
+	}
+	return layout, nil
+}
+
+// length returns the total length of a hash with the given layout.
+func (l hashLayout) length() int {
+	return l.headerLength + int(l.settings.payloadLength())
+}
+
+// salt returns the salt portion of the given hash data.
+func (l hashLayout) salt(data []byte) []byte {
+	return data[l.headerLength : l.headerLength+int(l.settings.SaltLength)]
+}
+
+// key returns the derived key portion of the given hash data.
+func (l hashLayout) key(data []byte) []byte {
+	start := l.headerLength + int(l.settings.SaltLength)
+	return data[start : start+int(l.settings.KeyLength)]
+}
+
+// timestamp returns the creation timestamp portion of the given hash data. It returns nil if the
+// hash does not carry a timestamp.
+func (l hashLayout) timestamp(data []byte) []byte {
+	if !l.settings.Timestamped {
+		return nil
+	}
+	return data[l.length()-TimestampLength : l.length()]
 }
@@ -6,9 +6,21 @@ package argon2
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 var (
@@ -27,6 +39,13 @@ var (
 		SaltLength: 16,
 		KeyLength:  32,
 	}
+	testFastSettings = Settings{
+		Memory:     64,
+		Time:       1,
+		Threads:    1,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
 )
 
 func TestDerive(t *testing.T) {
@@ -35,7 +54,7 @@ func TestDerive(t *testing.T) {
 		if err != nil {
 			t.Fatalf("failed to derive hash from password string: %s", err.Error())
 		}
-		if len(derived) != SerializedSettingsLength+int(DefaultSettings.SaltLength+DefaultSettings.KeyLength) {
+		if len(derived) != DefaultSettings.HashLength() {
 			t.Fatal("derived hash is not the correct length")
 		}
 	})
@@ -44,23 +63,578 @@ func TestDerive(t *testing.T) {
 		if err != nil {
 			t.Fatalf("failed to derive hash from password string: %s", err.Error())
 		}
-		if len(derived) != SerializedSettingsLength+int(testSettings.SaltLength+testSettings.KeyLength) {
+		if len(derived) != testSettings.HashLength() {
+			t.Fatal("derived hash is not the correct length")
+		}
+	})
+	t.Run("Argon2I derive succeeds with test settings", func(t *testing.T) {
+		settings := testSettings
+		settings.Variant = VariantI
+		derived, err := Derive(testPassPhrase, settings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err.Error())
+		}
+		if len(derived) != settings.HashLength() {
 			t.Fatal("derived hash is not the correct length")
 		}
+		if derived[10] != byte(VariantI) {
+			t.Errorf("derived hash variant is not as expected, got: %d, want: %d", derived[10], VariantI)
+		}
+	})
+	t.Run("derive fails with unsupported version", func(t *testing.T) {
+		settings := testSettings
+		settings.Version = 0x10
+		if _, err := Derive(testPassPhrase, settings); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatalf("derive should have failed with unsupported version, got: %v", err)
+		}
+	})
+	t.Run("Argon2D derive fails as unsupported", func(t *testing.T) {
+		settings := testSettings
+		settings.Variant = VariantD
+		if _, err := Derive(testPassPhrase, settings); !errors.Is(err, ErrUnsupportedVariant) {
+			t.Fatalf("derive should have failed with unsupported Argon2d variant, got: %v", err)
+		}
+	})
+	t.Run("derive fails with unknown variant", func(t *testing.T) {
+		settings := testSettings
+		settings.Variant = 99
+		if _, err := Derive(testPassPhrase, settings); !errors.Is(err, ErrUnsupportedVariant) {
+			t.Fatalf("derive should have failed with unknown variant, got: %v", err)
+		}
+	})
+	t.Run("derive fails with invalid settings", func(t *testing.T) {
+		settings := testSettings
+		settings.Threads = 0
+		_, err := Derive(testPassPhrase, settings)
+		var settingErr *InvalidSettingError
+		if !errors.As(err, &settingErr) {
+			t.Fatalf("derive should have failed with an InvalidSettingError, got: %v", err)
+		}
 	})
 	t.Run("Argon2ID derive fails with broken reader", func(t *testing.T) {
-		originalRandReader := rand.Reader
-		t.Cleanup(func() {
-			rand.Reader = originalRandReader
+		_, err := DeriveWithRand(testPassPhrase, testSettings, failReader{})
+		if !errors.Is(err, ErrSaltGeneration) {
+			t.Fatalf("derive should have failed with salt generation error, got: %v", err)
+		}
+	})
+}
+
+func TestMaxPasswordLength(t *testing.T) {
+	maxPassword := strings.Repeat("a", MaxPasswordLength)
+	tooLong := maxPassword + "a"
+
+	t.Run("derive with maximum length succeeds", func(t *testing.T) {
+		derived, err := Derive(maxPassword, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.Validate(maxPassword) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive with too long password fails", func(t *testing.T) {
+		if _, err := Derive(tooLong, testFastSettings); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("derive should have failed with ErrPasswordTooLong, got: %v", err)
+		}
+		if _, err := DeriveKey([]byte(tooLong), []byte("somesalt"), testFastSettings); !errors.Is(err,
+			ErrPasswordTooLong) {
+			t.Errorf("derive key should have failed with ErrPasswordTooLong, got: %v", err)
+		}
+	})
+	t.Run("validate with too long password fails", func(t *testing.T) {
+		derived, err := Derive(maxPassword, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if err = derived.VerifyPassword(tooLong); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("verification should have failed with ErrPasswordTooLong, got: %v", err)
+		}
+	})
+	t.Run("zero maximum length disables the check", func(t *testing.T) {
+		opts := []Option{WithMemory(testFastSettings.Memory), WithThreads(testFastSettings.Threads),
+			WithTime(testFastSettings.Time), WithMaxPasswordLength(0)}
+		derived, err := DeriveWithOptions(tooLong, opts...)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateWithOptions(tooLong, opts...) {
+			t.Error("derived hash is not valid but should be")
+		}
+		if derived.Validate(tooLong) {
+			t.Error("derived hash should not be valid with the default maximum length")
+		}
+	})
+	t.Run("custom maximum length is applied", func(t *testing.T) {
+		opts := []Option{WithMemory(testFastSettings.Memory), WithThreads(testFastSettings.Threads),
+			WithTime(testFastSettings.Time), WithMaxPasswordLength(8)}
+		if _, err := DeriveWithOptions("123456789", opts...); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("derive should have failed with ErrPasswordTooLong, got: %v", err)
+		}
+		derived, err := DeriveWithOptions("12345678", opts...)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateWithOptions("12345678", opts...) {
+			t.Error("derived hash is not valid but should be")
+		}
+		if derived.ValidateWithOptions("123456789", opts...) {
+			t.Error("derived hash should not be valid with a too long password")
+		}
+	})
+}
+
+func TestDeriveWithRand(t *testing.T) {
+	t.Run("derive with deterministic reader is reproducible", func(t *testing.T) {
+		salt := Argon2(testDerived).Salt()
+		derived, err := DeriveWithRand(testPassPhrase, testSettings, bytes.NewReader(salt))
+		if err != nil {
+			t.Fatalf("failed to derive hash with custom reader: %s", err)
+		}
+		if !bytes.Equal(derived.Salt(), salt) {
+			t.Errorf("salt is not as expected, got: %x, want: %x", derived.Salt(), salt)
+		}
+		if !bytes.Equal(derived.Key(), Argon2(testDerived).Key()) {
+			t.Errorf("key is not as expected, got: %x, want: %x", derived.Key(), Argon2(testDerived).Key())
+		}
+	})
+	t.Run("derive with nil reader uses crypto/rand", func(t *testing.T) {
+		derived, err := DeriveWithRand(testPassPhrase, testFastSettings, nil)
+		if err != nil {
+			t.Fatalf("failed to derive hash with nil reader: %s", err)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive with broken reader fails", func(t *testing.T) {
+		_, err := DeriveWithRand(testPassPhrase, testFastSettings, failReader{})
+		if !errors.Is(err, ErrSaltGeneration) {
+			t.Fatalf("derive should have failed with salt generation error, got: %v", err)
+		}
+	})
+	t.Run("derive with all-zero reader fails", func(t *testing.T) {
+		_, err := DeriveWithRand(testPassPhrase, testFastSettings, zeroReader{})
+		if !errors.Is(err, ErrZeroSalt) || !errors.Is(err, ErrSaltGeneration) {
+			t.Fatalf("derive should have failed with zero salt error, got: %v", err)
+		}
+	})
+	t.Run("derive retries once on an all-zero salt", func(t *testing.T) {
+		random := io.MultiReader(bytes.NewReader(make([]byte, testFastSettings.SaltLength)), rand.Reader)
+		derived, err := DeriveWithRand(testPassPhrase, testFastSettings, random)
+		if err != nil {
+			t.Fatalf("derive should have succeeded on the second attempt, got: %s", err)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive with short reader fails", func(t *testing.T) {
+		_, err := DeriveWithRand(testPassPhrase, testFastSettings, bytes.NewReader([]byte{0x01, 0x02}))
+		if !errors.Is(err, ErrSaltGeneration) {
+			t.Fatalf("derive should have failed with salt generation error, got: %v", err)
+		}
+	})
+}
+
+func TestDeriveWithSaltFunc(t *testing.T) {
+	t.Run("derive with salt function uses the returned salt", func(t *testing.T) {
+		salt := Argon2(testDerived).Salt()
+		var requested uint32
+		derived, err := DeriveWithSaltFunc(testPassPhrase, testSettings, func(n uint32) ([]byte, error) {
+			requested = n
+			return salt, nil
+		})
+		if err != nil {
+			t.Fatalf("failed to derive hash with salt function: %s", err)
+		}
+		if requested != testSettings.SaltLength {
+			t.Errorf("salt function called with wrong length, got: %d, want: %d", requested,
+				testSettings.SaltLength)
+		}
+		if !bytes.Equal(derived.Key(), Argon2(testDerived).Key()) {
+			t.Errorf("key is not as expected, got: %x, want: %x", derived.Key(), Argon2(testDerived).Key())
+		}
+	})
+	t.Run("derive with nil salt function uses crypto/rand", func(t *testing.T) {
+		derived, err := DeriveWithSaltFunc(testPassPhrase, testFastSettings, nil)
+		if err != nil {
+			t.Fatalf("failed to derive hash with nil salt function: %s", err)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive with failing salt function fails", func(t *testing.T) {
+		_, err := DeriveWithSaltFunc(testPassPhrase, testFastSettings, func(uint32) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		})
+		if !errors.Is(err, ErrSaltGeneration) || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("derive should have failed with salt generation error, got: %v", err)
+		}
+	})
+	t.Run("derive with wrong salt length fails", func(t *testing.T) {
+		_, err := DeriveWithSaltFunc(testPassPhrase, testFastSettings, func(n uint32) ([]byte, error) {
+			return bytes.Repeat([]byte{0x01}, int(n)-1), nil
+		})
+		if !errors.Is(err, ErrSaltGeneration) {
+			t.Fatalf("derive should have failed with salt generation error, got: %v", err)
+		}
+	})
+	t.Run("derive with all-zero salt fails", func(t *testing.T) {
+		_, err := DeriveWithSaltFunc(testPassPhrase, testFastSettings, func(n uint32) ([]byte, error) {
+			return make([]byte, n), nil
+		})
+		if !errors.Is(err, ErrZeroSalt) {
+			t.Fatalf("derive should have failed with zero salt error, got: %v", err)
+		}
+	})
+	t.Run("derive with invalid settings does not call salt function", func(t *testing.T) {
+		settings := testFastSettings
+		settings.KeyLength = 0
+		_, err := DeriveWithSaltFunc(testPassPhrase, settings, func(uint32) ([]byte, error) {
+			t.Error("salt function should not be called")
+			return nil, nil
 		})
-		rand.Reader = failReader{}
-		_, err := Derive(testPassPhrase, testSettings)
 		if err == nil {
+			t.Fatal("derive with invalid settings should have failed")
+		}
+	})
+}
+
+func TestDeriveWithSalt(t *testing.T) {
+	t.Run("derive with salt reproduces the hash", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		derived, err := DeriveWithSalt(testPassPhrase, argon.Salt(), testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with salt: %s", err)
+		}
+		if !bytes.Equal(derived.Key(), argon.Key()) {
+			t.Errorf("key is not as expected, got: %x, want: %x", derived.Key(), argon.Key())
+		}
+	})
+	t.Run("derive with salt uses the salt length", func(t *testing.T) {
+		salt := []byte("a salt of 24 bytes......")
+		derived, err := DeriveWithSalt(testPassPhrase, salt, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with salt: %s", err)
+		}
+		if !bytes.Equal(derived.Salt(), salt) {
+			t.Errorf("salt is not as expected, got: %x, want: %x", derived.Salt(), salt)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive with salt matches reference output", func(t *testing.T) {
+		settings := NewSettings(64, 1, 1, 0, 24)
+		derived, err := DeriveWithSalt("password", []byte("somesalt"), settings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with salt: %s", err)
+		}
+		want := "655ad15eac652dc59f7170a7332bf49b8469be1fdb9c28bb"
+		if hex.EncodeToString(derived.Key()) != want {
+			t.Errorf("key is not as expected, got: %x, want: %s", derived.Key(), want)
+		}
+	})
+	t.Run("derive with empty salt fails", func(t *testing.T) {
+		if _, err := DeriveWithSalt(testPassPhrase, nil, testFastSettings); err == nil {
+			t.Error("deriving hash with empty salt should fail")
+		}
+	})
+	t.Run("derive with too short salt fails", func(t *testing.T) {
+		if _, err := DeriveWithSalt(testPassPhrase, []byte("salt"), testFastSettings); err == nil {
+			t.Error("deriving hash with too short salt should fail")
+		}
+	})
+	t.Run("derived key depends on threads", func(t *testing.T) {
+		salt := Argon2(testDerived).Salt()
+		single, err := DeriveWithSalt(testPassPhrase, salt, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		settings := testFastSettings
+		settings.Threads = 2
+		parallel, err := DeriveWithSalt(testPassPhrase, salt, settings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if bytes.Equal(single.Key(), parallel.Key()) {
+			t.Error("keys derived with different threads should differ")
+		}
+	})
+}
+
+// TestDeriveWithSalt_ReferenceVectors locks down the derivation against known answers of the
+// Argon2 reference implementation for version 0x13, using the password "password" and the salt
+// "somesalt". The test vectors of RFC 9106 itself cannot be used, since they require a secret key
+// and associated data, which are not supported by golang.org/x/crypto/argon2.
+func TestDeriveWithSalt_ReferenceVectors(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		time    uint32
+		memory  uint32
+		threads uint8
+		key     string
+	}{
+		{VariantI, 1, 64, 1, "b9c401d1844a67d50eae3967dc28870b22e508092e861a37"},
+		{VariantID, 1, 64, 1, "655ad15eac652dc59f7170a7332bf49b8469be1fdb9c28bb"},
+		{VariantI, 2, 64, 1, "8cf3d8f76a6617afe35fac48eb0b7433a9a670ca4a07ed64"},
+		{VariantID, 2, 64, 1, "068d62b26455936aa6ebe60060b0a65870dbfa3ddf8d41f7"},
+		{VariantI, 2, 64, 2, "2089f3e78a799720f80af806553128f29b132cafe40d059f"},
+		{VariantID, 2, 64, 2, "350ac37222f436ccb5c0972f1ebd3bf6b958bf2071841362"},
+		{VariantI, 3, 256, 2, "f5bbf5d4c3836af13193053155b73ec7476a6a2eb93fd5e6"},
+		{VariantID, 3, 256, 2, "4668d30ac4187e6878eedeacf0fd83c5a0a30db2cc16ef0b"},
+		{VariantI, 4, 4096, 4, "a11f7b7f3f93f02ad4bddb59ab62d121e278369288a0d0e7"},
+		{VariantID, 4, 4096, 4, "145db9733a9f4ee43edf33c509be96b934d505a4efb33c5a"},
+		{VariantI, 4, 1024, 8, "0cdd3956aa35e6b475a7b0c63488822f774f15b43f6e6e17"},
+		{VariantID, 4, 1024, 8, "8dafa8e004f8ea96bf7c0f93eecf67a6047476143d15577f"},
+		{VariantI, 2, 64, 3, "5cab452fe6b8479c8661def8cd703b611a3905a6d5477fe6"},
+		{VariantID, 2, 64, 3, "4a15b31aec7c2590b87d1f520be7d96f56658172deaa3079"},
+		{VariantI, 3, 1024, 6, "d236b29c2b2a09babee842b0dec6aa1e83ccbdea8023dced"},
+		{VariantID, 3, 1024, 6, "1640b932f4b60e272f5d2207b9a9c626ffa1bd88d2349016"},
+	}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s t=%d m=%d p=%d", tt.variant, tt.time, tt.memory, tt.threads)
+		t.Run(name, func(t *testing.T) {
+			want, err := hex.DecodeString(tt.key)
+			if err != nil {
+				t.Fatalf("failed to decode reference key: %s", err)
+			}
+			settings := Settings{
+				Memory:    tt.memory,
+				Time:      tt.time,
+				Threads:   tt.threads,
+				KeyLength: uint32(len(want)),
+				Variant:   tt.variant,
+			}
+			derived, err := DeriveWithSalt("password", []byte("somesalt"), settings)
+			if err != nil {
+				t.Fatalf("failed to derive hash: %s", err)
+			}
+			if !bytes.Equal(derived.Key(), want) {
+				t.Errorf("derived key does not match the reference, got: %x, want: %x", derived.Key(), want)
+			}
+			if !derived.Validate("password") {
+				t.Error("derived hash is not valid but should be")
+			}
+		})
+	}
+}
+
+func TestDeriveContext(t *testing.T) {
+	t.Run("derive with context", func(t *testing.T) {
+		derived, err := DeriveContext(context.Background(), testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with context: %s", err)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive with cancelled context fails", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := DeriveContext(ctx, testPassPhrase, testFastSettings)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("derive should have failed with context cancellation, got: %v", err)
+		}
+	})
+	t.Run("derive with expiring context fails", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		_, err := DeriveContext(ctx, testPassPhrase, testSettings)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("derive should have failed with deadline exceeded, got: %v", err)
+		}
+	})
+	t.Run("derive with context and invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Threads = 0
+		if _, err := DeriveContext(context.Background(), testPassPhrase, settings); err == nil {
+			t.Error("derive with invalid settings should fail")
+		}
+	})
+}
+
+func TestDeriveKey(t *testing.T) {
+	t.Run("derive key matches the key of the hash", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		key, err := DeriveKey([]byte(testPassPhrase), argon.Salt(), testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive key: %s", err)
+		}
+		if !bytes.Equal(key, argon.Key()) {
+			t.Errorf("derived key is not as expected, got: %x, want: %x", key, argon.Key())
+		}
+	})
+	t.Run("derive key with reference vector", func(t *testing.T) {
+		settings := NewSettings(64, 1, 1, 0, 24)
+		key, err := DeriveKey([]byte("password"), []byte("somesalt"), settings)
+		if err != nil {
+			t.Fatalf("failed to derive key: %s", err)
+		}
+		want := "655ad15eac652dc59f7170a7332bf49b8469be1fdb9c28bb"
+		if hex.EncodeToString(key) != want {
+			t.Errorf("derived key is not as expected, got: %x, want: %s", key, want)
+		}
+	})
+	t.Run("derive key with too short salt fails", func(t *testing.T) {
+		if _, err := DeriveKey([]byte(testPassPhrase), []byte("salt"), testFastSettings); err == nil {
+			t.Error("deriving key with too short salt should fail")
+		}
+	})
+	t.Run("derive key with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Time = 0
+		if _, err := DeriveKey([]byte(testPassPhrase), []byte("somesalt"), settings); err == nil {
+			t.Error("deriving key with invalid settings should fail")
+		}
+	})
+}
+
+func TestDeriveKeys(t *testing.T) {
+	salt := []byte("somesalt")
+	t.Run("derived keys are segments of a single derivation", func(t *testing.T) {
+		settings := testFastSettings
+		settings.KeyLength = 64
+		want, err := DeriveKey([]byte(testPassPhrase), salt, settings)
+		if err != nil {
+			t.Fatalf("failed to derive key: %s", err)
+		}
+		keys, err := DeriveKeys([]byte(testPassPhrase), salt, testFastSettings, 32, 16, 16)
+		if err != nil {
+			t.Fatalf("failed to derive keys: %s", err)
+		}
+		if len(keys) != 3 {
+			t.Fatalf("unexpected number of keys, got: %d, want: %d", len(keys), 3)
+		}
+		if !bytes.Equal(bytes.Join(keys, nil), want) {
+			t.Errorf("derived keys are not as expected, got: %x, want: %x", bytes.Join(keys, nil), want)
+		}
+		if bytes.Equal(keys[1], keys[2]) {
+			t.Error("derived keys should differ from each other")
+		}
+	})
+	t.Run("appending to a key does not overwrite the next key", func(t *testing.T) {
+		keys, err := DeriveKeys([]byte(testPassPhrase), salt, testFastSettings, 16, 16)
+		if err != nil {
+			t.Fatalf("failed to derive keys: %s", err)
+		}
+		second := bytes.Clone(keys[1])
+		_ = append(keys[0], 0xff)
+		if !bytes.Equal(keys[1], second) {
+			t.Error("appending to the first key should not modify the second key")
+		}
+	})
+	t.Run("derive keys with invalid lengths fails", func(t *testing.T) {
+		for name, lengths := range map[string][]uint32{
+			"no key lengths":   nil,
+			"zero key length":  {32, 0},
+			"too long keys":    {MaxKeyLength, 1},
+			"overflowing keys": {math.MaxUint32, math.MaxUint32},
+		} {
+			if _, err := DeriveKeys([]byte(testPassPhrase), salt, testFastSettings, lengths...); err == nil {
+				t.Errorf("deriving keys with %s should fail", name)
+			}
+		}
+	})
+}
+
+func TestMustDerive(t *testing.T) {
+	t.Run("must derive succeeds", func(t *testing.T) {
+		derived := MustDerive(testPassPhrase, testFastSettings)
+		if !derived.Validate(testPassPhrase) {
+			t.Fatal("derived hash is not valid but should be")
+		}
+	})
+	t.Run("must derive panics with invalid settings", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("must derive should have panicked with invalid settings")
+			}
+		}()
+		settings := testFastSettings
+		settings.Threads = 0
+		_ = MustDerive(testPassPhrase, settings)
+	})
+}
+
+func TestDeriveBytes(t *testing.T) {
+	t.Run("derive from byte slice succeeds", func(t *testing.T) {
+		password := []byte(testPassPhrase)
+		derived, err := DeriveBytes(password, testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password byte slice: %s", err)
+		}
+		for i := range password {
+			password[i] = 0
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Fatal("derived hash is not valid after wiping the password buffer")
+		}
+	})
+	t.Run("derive from byte slice fails with broken reader", func(t *testing.T) {
+		if _, err := derive([]byte(testPassPhrase), nil, options{settings: testSettings}, failReader{}); err == nil {
 			t.Fatal("derive should have failed with broken reader")
 		}
 	})
 }
 
+func TestDeriveReader(t *testing.T) {
+	t.Run("derive from reader succeeds", func(t *testing.T) {
+		derived, err := DeriveReader(strings.NewReader(testPassPhrase), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from reader: %s", err)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Fatal("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive from reader with maximum length succeeds", func(t *testing.T) {
+		secret := bytes.Repeat([]byte("a"), MaxReaderSecretLength)
+		derived, err := DeriveReader(bytes.NewReader(secret), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from reader: %s", err)
+		}
+		if !derived.ValidateReader(bytes.NewReader(secret)) {
+			t.Fatal("derived hash is not valid but should be")
+		}
+	})
+	t.Run("derive from reader is not subject to the maximum password length", func(t *testing.T) {
+		secret := bytes.Repeat([]byte("a"), 4096)
+		derived, err := DeriveReader(bytes.NewReader(secret), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from reader: %s", err)
+		}
+		if !derived.ValidateReader(bytes.NewReader(secret)) {
+			t.Error("derived hash is not valid with the reader but should be")
+		}
+		if derived.ValidateBytes(secret) {
+			t.Error("derived hash should not be valid as a password exceeding the maximum length")
+		}
+	})
+	t.Run("derive from reader exceeding the maximum length fails", func(t *testing.T) {
+		secret := bytes.Repeat([]byte("a"), MaxReaderSecretLength+1)
+		if _, err := DeriveReader(bytes.NewReader(secret), testFastSettings); err == nil {
+			t.Fatal("derive from too long reader should have failed")
+		}
+	})
+	t.Run("derive from broken reader fails", func(t *testing.T) {
+		if _, err := DeriveReader(failReader{}, testFastSettings); err == nil {
+			t.Fatal("derive from broken reader should have failed")
+		}
+	})
+	t.Run("validate from broken reader fails", func(t *testing.T) {
+		derived, err := DeriveReader(strings.NewReader(testPassPhrase), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from reader: %s", err)
+		}
+		if derived.ValidateReader(failReader{}) {
+			t.Error("validation from broken reader should have failed")
+		}
+	})
+}
+
 func TestArgon2_Salt(t *testing.T) {
 	t.Run("salt with static values", func(t *testing.T) {
 		argon := Argon2(testDerived)
@@ -73,6 +647,17 @@ func TestArgon2_Salt(t *testing.T) {
 			t.Errorf("salt is not as expected, got: %x, want: %x", salt, want)
 		}
 	})
+	t.Run("salt with derived value", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err.Error())
+		}
+		salt := derived.Salt()
+		want := derived[SerializedSettingsLength : SerializedSettingsLength+int(testSettings.SaltLength)]
+		if !bytes.Equal(salt, want) {
+			t.Errorf("salt is not as expected, got: %x, want: %x", salt, want)
+		}
+	})
 	t.Run("salt with nil value", func(t *testing.T) {
 		argon := Argon2{}
 		salt := argon.Salt()
@@ -97,6 +682,17 @@ func TestArgon2_Key(t *testing.T) {
 			t.Errorf("key is not as expected, got: %x, want: %x", key, want)
 		}
 	})
+	t.Run("key with derived value", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err.Error())
+		}
+		key := derived.Key()
+		want := derived[SerializedSettingsLength+int(testSettings.SaltLength):]
+		if !bytes.Equal(key, want) {
+			t.Errorf("key is not as expected, got: %x, want: %x", key, want)
+		}
+	})
 	t.Run("key with nil value", func(t *testing.T) {
 		argon := Argon2{}
 		key := argon.Key()
@@ -106,41 +702,1054 @@ func TestArgon2_Key(t *testing.T) {
 	})
 }
 
-func TestArgon2_Validate(t *testing.T) {
-	t.Run("validate succeeds", func(t *testing.T) {
-		derived, err := Derive(testPassPhrase, testSettings)
+func TestArgon2_SaltE(t *testing.T) {
+	t.Run("salt of valid hash", func(t *testing.T) {
+		salt, err := Argon2(testDerived).SaltE()
 		if err != nil {
-			t.Fatalf("failed to derive hash from password string: %s", err.Error())
+			t.Fatalf("failed to extract salt: %s", err)
 		}
-		if !derived.Validate(testPassPhrase) {
-			t.Fatal("derived hash is not valid but should be")
+		if !bytes.Equal(salt, Argon2(testDerived).Salt()) {
+			t.Errorf("salt is not as expected, got: %x, want: %x", salt, Argon2(testDerived).Salt())
 		}
 	})
-	t.Run("validate with static values succeeds", func(t *testing.T) {
-		argon := Argon2(testDerived)
-		if !argon.Validate(testPassPhrase) {
-			t.Fatal("derived hash is not valid but should be")
+	t.Run("salt of invalid hash fails", func(t *testing.T) {
+		tooLongSalt := testFastSettings
+		tooLongSalt.SaltLength = 1 << 20
+		for name, hash := range map[string]Argon2{
+			"empty hash":        nil,
+			"truncated hash":    testDerived[:len(testDerived)-2],
+			"salt exceeds data": tooLongSalt.Serialize(),
+			"header only":       testFastSettings.Serialize(),
+			"too short hash":    Argon2(testDerived[:LegacySerializedSettingsLength-1]),
+		} {
+			if _, err := hash.SaltE(); !errors.Is(err, ErrInvalidHashLength) {
+				t.Errorf("extracting salt of %s should fail with ErrInvalidHashLength, got: %v", name, err)
+			}
 		}
 	})
-	t.Run("validate on nil", func(t *testing.T) {
-		var argon Argon2
-		if argon.Validate(testPassPhrase) {
-			t.Fatal("validation on nil should have failed")
+}
+
+func TestArgon2_KeyE(t *testing.T) {
+	t.Run("key of valid hash", func(t *testing.T) {
+		key, err := Argon2(testDerived).KeyE()
+		if err != nil {
+			t.Fatalf("failed to extract key: %s", err)
+		}
+		if !bytes.Equal(key, Argon2(testDerived).Key()) {
+			t.Errorf("key is not as expected, got: %x, want: %x", key, Argon2(testDerived).Key())
 		}
 	})
-	t.Run("validate on invalid hash", func(t *testing.T) {
-		argon := Argon2(testDerived[:len(testDerived)-2])
-		if argon.Validate(testPassPhrase) {
-			t.Fatal("validation on invalid hash should have failed")
+	t.Run("key of invalid hash fails", func(t *testing.T) {
+		if _, err := Argon2(testDerived[:len(testDerived)-2]).KeyE(); !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("extracting key of invalid hash should fail with ErrInvalidHashLength, got: %v", err)
 		}
 	})
 }
 
-func BenchmarkDerive(b *testing.B) {
-	b.ReportAllocs()
-	for i := 0; i < b.N; i++ {
-		_, _ = Derive(testPassPhrase, DefaultSettings)
+func TestArgon2_Equal(t *testing.T) {
+	derived, err := Derive(testPassPhrase, testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash: %s", err)
 	}
+	t.Run("identical hashes are equal", func(t *testing.T) {
+		if !derived.Equal(derived.Clone()) {
+			t.Error("hash should be equal to its clone")
+		}
+	})
+	t.Run("different hashes are not equal", func(t *testing.T) {
+		modified := derived.Clone()
+		modified[len(modified)-1] ^= 0x01
+		if derived.Equal(modified) {
+			t.Error("hashes with different keys should not be equal")
+		}
+		if derived.Equal(derived[:len(derived)-1]) {
+			t.Error("hashes with different lengths should not be equal")
+		}
+	})
+	t.Run("empty hashes are equal", func(t *testing.T) {
+		if !Argon2(nil).Equal(Argon2{}) {
+			t.Error("nil and empty hashes should be equal")
+		}
+		if derived.Equal(nil) {
+			t.Error("hash should not be equal to a nil hash")
+		}
+	})
+}
+
+func TestArgon2_CreatedAt(t *testing.T) {
+	created := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+	derived, err := DeriveWithOptions(testPassPhrase, WithMemory(testFastSettings.Memory),
+		WithTime(testFastSettings.Time), WithThreads(testFastSettings.Threads), WithTimestampAt(created))
+	if err != nil {
+		t.Fatalf("failed to derive hash with timestamp: %s", err)
+	}
+	t.Run("timestamped hash returns the creation time", func(t *testing.T) {
+		createdAt, ok := derived.CreatedAt()
+		if !ok {
+			t.Fatal("timestamped hash should have a creation time")
+		}
+		if !createdAt.Equal(created) {
+			t.Errorf("creation time is not as expected, got: %s, want: %s", createdAt, created)
+		}
+	})
+	t.Run("timestamped hash validates", func(t *testing.T) {
+		if !derived.Validate(testPassPhrase) {
+			t.Error("timestamped hash is not valid but should be")
+		}
+		if derived.Validate("wrong password") {
+			t.Error("timestamped hash should not validate a wrong password")
+		}
+	})
+	t.Run("timestamped hash settings", func(t *testing.T) {
+		settings, err := derived.Settings()
+		if err != nil {
+			t.Fatalf("failed to get settings: %s", err)
+		}
+		if !settings.Timestamped {
+			t.Error("settings of timestamped hash should be timestamped")
+		}
+		if settings.HashLength() != len(derived) {
+			t.Errorf("hash length is not as expected, got: %d, want: %d", settings.HashLength(), len(derived))
+		}
+		if len(derived.Key()) != int(settings.KeyLength) {
+			t.Errorf("key length is not as expected, got: %d, want: %d", len(derived.Key()), settings.KeyLength)
+		}
+	})
+	t.Run("timestamp defaults to the current time", func(t *testing.T) {
+		before := time.Now().Truncate(time.Second)
+		hash, err := DeriveWithOptions(testPassPhrase, WithMemory(testFastSettings.Memory),
+			WithTime(testFastSettings.Time), WithThreads(testFastSettings.Threads), WithTimestamp(true))
+		if err != nil {
+			t.Fatalf("failed to derive hash with timestamp: %s", err)
+		}
+		createdAt, ok := hash.CreatedAt()
+		if !ok || createdAt.Before(before) || createdAt.After(time.Now()) {
+			t.Errorf("creation time should be the current time, got: %s", createdAt)
+		}
+	})
+	t.Run("hash without timestamp has no creation time", func(t *testing.T) {
+		plain, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if _, ok := plain.CreatedAt(); ok {
+			t.Error("hash without timestamp should not have a creation time")
+		}
+	})
+	t.Run("legacy hash has no creation time", func(t *testing.T) {
+		if _, ok := Argon2(testDerived).CreatedAt(); ok {
+			t.Error("legacy hash should not have a creation time")
+		}
+	})
+	t.Run("truncated timestamped hash is invalid", func(t *testing.T) {
+		truncated := derived[:len(derived)-TimestampLength]
+		if _, ok := truncated.CreatedAt(); ok {
+			t.Error("truncated hash should not have a creation time")
+		}
+		if truncated.Validate(testPassPhrase) {
+			t.Error("truncated hash should not validate")
+		}
+	})
+}
+
+func TestArgon2_SaltEqual(t *testing.T) {
+	derived, err := Derive(testPassPhrase, testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash: %s", err)
+	}
+	t.Run("same salt is equal", func(t *testing.T) {
+		if !derived.SaltEqual(derived) {
+			t.Error("hash should have the same salt as itself")
+		}
+		if !Argon2(testDerived).SaltEqual(testDerived) {
+			t.Error("static hash should have the same salt as itself")
+		}
+	})
+	t.Run("reused salt with different password is equal", func(t *testing.T) {
+		reused, err := DeriveWithRand("other password", testFastSettings, bytes.NewReader(derived.Salt()))
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.SaltEqual(reused) {
+			t.Error("hashes with reused salt should have equal salts")
+		}
+	})
+	t.Run("different salts are not equal", func(t *testing.T) {
+		if derived.SaltEqual(testDerived) {
+			t.Error("hashes with different salts should not be equal")
+		}
+	})
+	t.Run("invalid hashes are not equal", func(t *testing.T) {
+		if derived.SaltEqual(nil) || Argon2(nil).SaltEqual(derived) || Argon2(nil).SaltEqual(nil) {
+			t.Error("invalid hashes should never have equal salts")
+		}
+	})
+}
+
+func TestArgon2_Settings(t *testing.T) {
+	t.Run("settings with static values", func(t *testing.T) {
+		settings, err := Argon2(testDerived).Settings()
+		if err != nil {
+			t.Fatalf("failed to extract settings: %s", err)
+		}
+		if !settings.Equal(testSettings) {
+			t.Errorf("settings are not as expected, got: %+v, want: %+v", settings, testSettings)
+		}
+	})
+	t.Run("settings with derived value", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err)
+		}
+		settings, err := derived.Settings()
+		if err != nil {
+			t.Fatalf("failed to extract settings: %s", err)
+		}
+		if !settings.Equal(testFastSettings) {
+			t.Errorf("settings are not as expected, got: %+v, want: %+v", settings, testFastSettings)
+		}
+	})
+	t.Run("settings with too short value fails", func(t *testing.T) {
+		for _, length := range []int{0, 1, LegacySerializedSettingsLength - 1} {
+			_, err := Argon2(make([]byte, length)).Settings()
+			if !errors.Is(err, ErrInvalidHashLength) {
+				t.Errorf("extracting settings from %d bytes should have failed with length error, got: %v",
+					length, err)
+			}
+		}
+	})
+	t.Run("settings with truncated value fails", func(t *testing.T) {
+		_, err := Argon2(testDerived[:len(testDerived)-1]).Settings()
+		if !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("extracting settings from truncated hash should have failed with length error, got: %v", err)
+		}
+	})
+}
+
+func TestArgon2_IsValid(t *testing.T) {
+	t.Run("static hash is valid", func(t *testing.T) {
+		if !Argon2(testDerived).IsValid() {
+			t.Error("static hash should be valid")
+		}
+	})
+	t.Run("derived hash is valid", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.IsValid() {
+			t.Error("derived hash should be valid")
+		}
+	})
+	t.Run("invalid hashes are not valid", func(t *testing.T) {
+		zeroThreads := bytes.Clone(testDerived)
+		zeroThreads[8] = 0
+		hugeMemory := testFastSettings
+		hugeMemory.Memory = math.MaxUint32
+		tests := []struct {
+			name string
+			hash Argon2
+		}{
+			{"nil hash", nil},
+			{"too short hash", testDerived[:LegacySerializedSettingsLength-1]},
+			{"truncated hash", testDerived[:len(testDerived)-2]},
+			{"header only", testFastSettings.Serialize()},
+			{"invalid settings", zeroThreads},
+			{"huge memory", newHash(hugeMemory, make([]byte, 16), make([]byte, 32))},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if tt.hash.IsValid() {
+					t.Errorf("hash %x should not be valid", tt.hash)
+				}
+			})
+		}
+	})
+}
+
+func TestArgon2_Hex(t *testing.T) {
+	t.Run("hex with static values", func(t *testing.T) {
+		want := "0000040001000000040010000000200000" +
+			"00f2e09facf9e21a226894a1d97bd6cffbaebd762180c17c87bdbd57a9efb2c9b79f810daf4fab55b67a705fed5221dfb3"
+		if got := Argon2(testDerived).Hex(); got != want {
+			t.Errorf("hex is not as expected, got: %s, want: %s", got, want)
+		}
+	})
+	t.Run("hex with nil value", func(t *testing.T) {
+		var argon Argon2
+		if got := argon.Hex(); got != "" {
+			t.Errorf("hex of nil hash is not empty, got: %s", got)
+		}
+	})
+}
+
+func TestArgon2_Base64URL(t *testing.T) {
+	t.Run("base64url round-trips", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		encoded := derived.Base64URL()
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("base64url encoding contains characters that are not URL-safe: %s", encoded)
+		}
+		parsed, err := ParseBase64URL(encoded)
+		if err != nil {
+			t.Fatalf("failed to parse base64url encoded hash: %s", err)
+		}
+		if !bytes.Equal(parsed, derived) {
+			t.Errorf("parsed hash does not match, got: %x, want: %x", []byte(parsed), []byte(derived))
+		}
+		if !parsed.Validate(testPassPhrase) {
+			t.Error("parsed hash is not valid but should be")
+		}
+	})
+	t.Run("base64url with legacy hash round-trips", func(t *testing.T) {
+		parsed, err := ParseBase64URL(Argon2(testDerived).Base64URL())
+		if err != nil {
+			t.Fatalf("failed to parse base64url encoded hash: %s", err)
+		}
+		if !bytes.Equal(parsed, testDerived) {
+			t.Errorf("parsed hash does not match, got: %x, want: %x", []byte(parsed), testDerived)
+		}
+	})
+	t.Run("parse with invalid base64 fails", func(t *testing.T) {
+		if _, err := ParseBase64URL("not+base64url="); err == nil {
+			t.Error("parsing invalid base64url should fail")
+		}
+	})
+	t.Run("parse with truncated hash fails", func(t *testing.T) {
+		encoded := Argon2(testDerived[:len(testDerived)-2]).Base64URL()
+		if _, err := ParseBase64URL(encoded); !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("parsing truncated hash should fail with ErrInvalidHashLength, got: %v", err)
+		}
+	})
+	t.Run("parse with invalid settings fails", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		derived[9] = 0
+		var settingErr *InvalidSettingError
+		if _, err = ParseBase64URL(derived.Base64URL()); !errors.As(err, &settingErr) {
+			t.Errorf("parsing hash with invalid settings should fail with InvalidSettingError, got: %v", err)
+		}
+	})
+	t.Run("parse with empty string fails", func(t *testing.T) {
+		if _, err := ParseBase64URL(""); !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("parsing empty string should fail with ErrInvalidHashLength, got: %v", err)
+		}
+	})
+}
+
+func TestArgon2_EmbeddedNULBytes(t *testing.T) {
+	password := "pass\x00word\x00"
+	t.Run("string API is binary-safe", func(t *testing.T) {
+		derived, err := Derive(password, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.Validate(password) {
+			t.Error("derived hash is not valid but should be")
+		}
+		if !derived.ValidateBytes([]byte(password)) {
+			t.Error("derived hash is not valid with the byte slice API but should be")
+		}
+	})
+	t.Run("byte slice API is binary-safe", func(t *testing.T) {
+		derived, err := DeriveBytes([]byte(password), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateBytes([]byte(password)) {
+			t.Error("derived hash is not valid but should be")
+		}
+		if !derived.Validate(password) {
+			t.Error("derived hash is not valid with the string API but should be")
+		}
+	})
+	t.Run("truncated passwords fail", func(t *testing.T) {
+		derived, err := Derive(password, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		for _, truncated := range []string{"pass", "pass\x00", "pass\x00word"} {
+			if derived.Validate(truncated) {
+				t.Errorf("hash should not be valid with truncated password %q", truncated)
+			}
+		}
+	})
+	t.Run("normalized password keeps NUL bytes", func(t *testing.T) {
+		derived, err := DeriveWithOptions(password, WithMemory(testFastSettings.Memory),
+			WithTime(testFastSettings.Time), WithThreads(testFastSettings.Threads),
+			WithUnicodeNormalization(norm.NFC))
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateWithOptions(password, WithUnicodeNormalization(norm.NFC)) {
+			t.Error("derived hash is not valid but should be")
+		}
+		if derived.ValidateWithOptions("pass", WithUnicodeNormalization(norm.NFC)) {
+			t.Error("hash should not be valid with a truncated password")
+		}
+	})
+}
+
+func TestArgon2_Validate(t *testing.T) {
+	t.Run("validate succeeds", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err.Error())
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Fatal("derived hash is not valid but should be")
+		}
+	})
+	t.Run("validate with static values succeeds", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		if !argon.Validate(testPassPhrase) {
+			t.Fatal("derived hash is not valid but should be")
+		}
+	})
+	t.Run("validate Argon2I hash succeeds", func(t *testing.T) {
+		settings := testSettings
+		settings.Variant = VariantI
+		derived, err := Derive(testPassPhrase, settings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err.Error())
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Fatal("derived hash is not valid but should be")
+		}
+		if derived.Validate("invalid") {
+			t.Fatal("derived hash is valid with wrong password")
+		}
+	})
+	t.Run("validate with modified variant fails", func(t *testing.T) {
+		argon := make(Argon2, len(testDerived))
+		copy(argon, testDerived)
+		argon[9] = byte(VariantI)
+		if argon.Validate(testPassPhrase) {
+			t.Fatal("validation with modified variant should have failed")
+		}
+		argon[9] = byte(VariantD)
+		if argon.Validate(testPassPhrase) {
+			t.Fatal("validation with unsupported variant should have failed")
+		}
+	})
+	t.Run("validate format version 0 hash succeeds", func(t *testing.T) {
+		argon := make(Argon2, 0, len(testDerived)+1)
+		argon = append(argon, testDerived[:LegacySerializedSettingsLength]...)
+		argon = append(argon, 0x13)
+		argon = append(argon, testDerived[LegacySerializedSettingsLength:]...)
+		if !argon.Validate(testPassPhrase) {
+			t.Fatal("migrated hash is not valid but should be")
+		}
+	})
+	t.Run("validate with unsupported version fails", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err.Error())
+		}
+		derived[SerializedSettingsLength-1] = 0x10
+		if derived.Validate(testPassPhrase) {
+			t.Fatal("validation with unsupported version should have failed")
+		}
+		err = derived.VerifyPassword(testPassPhrase)
+		if !errors.Is(err, ErrUnsupportedVersion) || !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("verification should have failed with unsupported version, got: %v", err)
+		}
+	})
+	t.Run("validate with out of range settings fails", func(t *testing.T) {
+		argon := make(Argon2, len(testDerived))
+		copy(argon, testDerived)
+		argon[4], argon[5], argon[6], argon[7] = 0x00, 0x00, 0x00, 0x00
+		if argon.Validate(testPassPhrase) {
+			t.Fatal("validation with zero time should have failed")
+		}
+	})
+	t.Run("validate on wiped hash fails", func(t *testing.T) {
+		argon := make(Argon2, len(testDerived))
+		copy(argon, testDerived)
+		argon.Zero()
+		if argon.Validate(testPassPhrase) {
+			t.Fatal("validation on wiped hash should have failed")
+		}
+	})
+	t.Run("validate on nil", func(t *testing.T) {
+		var argon Argon2
+		if argon.Validate(testPassPhrase) {
+			t.Fatal("validation on nil should have failed")
+		}
+	})
+	t.Run("validate on invalid hash", func(t *testing.T) {
+		argon := Argon2(testDerived[:len(testDerived)-2])
+		if argon.Validate(testPassPhrase) {
+			t.Fatal("validation on invalid hash should have failed")
+		}
+	})
+}
+
+func TestArgon2_DeriveScanValidateRoundTrip(t *testing.T) {
+	roundTrip := func(password string, q quickTinySettings) bool {
+		hash, err := Derive(password, Settings(q))
+		if err != nil {
+			t.Logf("failed to derive hash: %s", err)
+			return false
+		}
+		var scanned Argon2
+		if err = (&scanned).Scan([]byte(hash)); err != nil {
+			t.Logf("failed to scan hash: %s", err)
+			return false
+		}
+		settings, err := scanned.Settings()
+		if err != nil {
+			t.Logf("failed to read settings of scanned hash: %s", err)
+			return false
+		}
+		return scanned.Equal(hash) && settings.Equal(Settings(q)) &&
+			scanned.Validate(password) && !scanned.Validate(password+"x")
+	}
+	t.Run("random passwords survive a derive, scan and validate round trip", func(t *testing.T) {
+		if err := quick.Check(roundTrip, &quick.Config{MaxCount: 50}); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// quickTinySettings generates random, valid Settings with a tiny cost for property tests with
+// testing/quick that execute the Argon2 KDF.
+type quickTinySettings Settings
+
+// Generate implements the quick.Generator interface.
+func (quickTinySettings) Generate(r *mathrand.Rand, _ int) reflect.Value {
+	threads := uint8(1 + r.Intn(4))
+	settings := Settings{
+		Memory:     MinMemoryPerThread*uint32(threads) + uint32(r.Intn(64)),
+		Time:       MinTime + uint32(r.Intn(2)),
+		Threads:    threads,
+		SaltLength: MinSaltLength + uint32(r.Intn(25)),
+		KeyLength:  MinKeyLength + uint32(r.Intn(61)),
+		Variant:    Variant(r.Intn(int(VariantI) + 1)),
+	}
+	return reflect.ValueOf(quickTinySettings(settings))
+}
+
+func TestArgon2_VerifyPassword(t *testing.T) {
+	t.Run("verify succeeds", func(t *testing.T) {
+		if err := Argon2(testDerived).VerifyPassword(testPassPhrase); err != nil {
+			t.Fatalf("verification should have succeeded, got: %s", err)
+		}
+	})
+	t.Run("verify with wrong password fails with mismatch", func(t *testing.T) {
+		err := Argon2(testDerived).VerifyPassword("invalid")
+		if !errors.Is(err, ErrMismatchedHashAndPassword) {
+			t.Fatalf("verification should have failed with mismatch, got: %v", err)
+		}
+	})
+	t.Run("verify on invalid hash fails with invalid hash", func(t *testing.T) {
+		err := Argon2(testDerived[:len(testDerived)-2]).VerifyPassword(testPassPhrase)
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Fatalf("verification should have failed with invalid hash, got: %v", err)
+		}
+	})
+	t.Run("verify on hash with unsupported variant fails with invalid hash", func(t *testing.T) {
+		argon := bytes.Clone(testDerived)
+		argon[9] = byte(VariantD)
+		err := Argon2(argon).VerifyPassword(testPassPhrase)
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Fatalf("verification should have failed with invalid hash, got: %v", err)
+		}
+	})
+	t.Run("verify on nil fails with invalid hash", func(t *testing.T) {
+		var argon Argon2
+		if err := argon.VerifyPassword(testPassPhrase); !errors.Is(err, ErrInvalidHash) {
+			t.Fatalf("verification should have failed with invalid hash, got: %v", err)
+		}
+	})
+}
+
+func TestDummyValidate(t *testing.T) {
+	t.Run("dummy validate always fails", func(t *testing.T) {
+		if DummyValidate(testPassPhrase) {
+			t.Error("dummy validate should always return false")
+		}
+	})
+}
+
+func TestCheckCredential(t *testing.T) {
+	t.Run("check with matching password", func(t *testing.T) {
+		if !CheckCredential(testDerived, testPassPhrase) {
+			t.Error("credential check with matching password should succeed")
+		}
+	})
+	t.Run("check with wrong password", func(t *testing.T) {
+		if CheckCredential(testDerived, "wrong password") {
+			t.Error("credential check with wrong password should fail")
+		}
+	})
+	t.Run("check with nil hash", func(t *testing.T) {
+		if CheckCredential(nil, testPassPhrase) {
+			t.Error("credential check with nil hash should fail")
+		}
+	})
+}
+
+func TestValidateAny(t *testing.T) {
+	derived, err := Derive("new password", testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash: %s", err)
+	}
+	t.Run("validate any matches the old hash", func(t *testing.T) {
+		if !ValidateAny(testPassPhrase, testDerived, derived) {
+			t.Error("password should match the old hash")
+		}
+	})
+	t.Run("validate any matches the new hash", func(t *testing.T) {
+		if !ValidateAny("new password", testDerived, derived) {
+			t.Error("password should match the new hash")
+		}
+	})
+	t.Run("validate any with wrong password fails", func(t *testing.T) {
+		if ValidateAny("wrong password", derived, derived) {
+			t.Error("wrong password should not match any hash")
+		}
+	})
+	t.Run("validate any ignores invalid hashes", func(t *testing.T) {
+		if !ValidateAny("new password", derived[:len(derived)-2], derived) {
+			t.Error("password should match the valid hash")
+		}
+	})
+}
+
+func TestArgon2_ValidateBytes(t *testing.T) {
+	t.Run("validate byte slice succeeds", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		if !argon.ValidateBytes([]byte(testPassPhrase)) {
+			t.Fatal("hash is not valid but should be")
+		}
+	})
+	t.Run("validate byte slice with wrong password fails", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		if argon.ValidateBytes([]byte("invalid")) {
+			t.Fatal("hash is valid with wrong password")
+		}
+	})
+	t.Run("validate nil byte slice on nil hash fails", func(t *testing.T) {
+		var argon Argon2
+		if argon.ValidateBytes(nil) {
+			t.Fatal("validation on nil should have failed")
+		}
+	})
+}
+
+func TestArgon2_Zero(t *testing.T) {
+	t.Run("zero wipes the hash", func(t *testing.T) {
+		argon := make(Argon2, len(testDerived))
+		copy(argon, testDerived)
+		salt, key := argon.Salt(), argon.Key()
+		argon.Zero()
+		if !bytes.Equal(argon, make([]byte, len(testDerived))) {
+			t.Errorf("hash is not wiped after zero, got: %x", argon)
+		}
+		if !bytes.Equal(salt, testDerived[LegacySerializedSettingsLength:LegacySerializedSettingsLength+16]) {
+			t.Errorf("salt copy was modified by zero, got: %x", salt)
+		}
+		if !bytes.Equal(key, testDerived[LegacySerializedSettingsLength+16:]) {
+			t.Errorf("key copy was modified by zero, got: %x", key)
+		}
+	})
+	t.Run("zero wipes the full capacity", func(t *testing.T) {
+		data := make([]byte, len(testDerived))
+		copy(data, testDerived)
+		argon := Argon2(data[:SerializedSettingsLength])
+		argon.Zero()
+		if !bytes.Equal(data, make([]byte, len(testDerived))) {
+			t.Errorf("backing array is not wiped after zero, got: %x", data)
+		}
+	})
+	t.Run("zero on nil hash", func(t *testing.T) {
+		var argon Argon2
+		argon.Zero()
+		if argon != nil {
+			t.Error("nil hash should stay nil after zero")
+		}
+	})
+}
+
+func TestArgon2_Clone(t *testing.T) {
+	t.Run("clone is independent", func(t *testing.T) {
+		argon := Argon2(testDerived).Clone()
+		clone := argon.Clone()
+		if !bytes.Equal(clone, argon) {
+			t.Fatalf("clone is not equal to the original, got: %x, want: %x", []byte(clone), []byte(argon))
+		}
+		argon.Zero()
+		if !bytes.Equal(clone, testDerived) {
+			t.Error("wiping the original should not affect the clone")
+		}
+		if !clone.Validate(testPassPhrase) {
+			t.Error("clone is not valid but should be")
+		}
+	})
+	t.Run("clone of nil is nil", func(t *testing.T) {
+		if clone := Argon2(nil).Clone(); clone != nil {
+			t.Errorf("clone of nil hash should be nil, got: %x", []byte(clone))
+		}
+	})
+}
+
+func TestArgon2_NeedsRehash(t *testing.T) {
+	t.Run("hash matching the target does not need a rehash", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		if argon.NeedsRehash(testSettings) {
+			t.Error("hash matching the target settings should not need a rehash")
+		}
+	})
+	t.Run("hash with stronger settings does not need a rehash", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		target := testSettings
+		target.Memory = 64 * 1024
+		if argon.NeedsRehash(target) {
+			t.Error("hash with stronger settings than the target should not need a rehash")
+		}
+	})
+	t.Run("hash with weaker settings needs a rehash", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(*Settings)
+		}{
+			{"memory", func(s *Settings) { s.Memory *= 2 }},
+			{"time", func(s *Settings) { s.Time++ }},
+			{"threads", func(s *Settings) { s.Threads++ }},
+			{"salt length", func(s *Settings) { s.SaltLength++ }},
+			{"key length", func(s *Settings) { s.KeyLength++ }},
+			{"variant", func(s *Settings) { s.Variant = VariantI }},
+		}
+		argon := Argon2(testDerived)
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				target := testSettings
+				tt.modify(&target)
+				if !argon.NeedsRehash(target) {
+					t.Errorf("hash with weaker %s should need a rehash", tt.name)
+				}
+			})
+		}
+	})
+	t.Run("invalid hash needs a rehash", func(t *testing.T) {
+		argon := Argon2(testDerived[:len(testDerived)-1])
+		if !argon.NeedsRehash(testSettings) {
+			t.Error("invalid hash should need a rehash")
+		}
+	})
+	t.Run("nil hash needs a rehash", func(t *testing.T) {
+		var argon Argon2
+		if !argon.NeedsRehash(testSettings) {
+			t.Error("nil hash should need a rehash")
+		}
+	})
+}
+
+func TestArgon2_UsesSettings(t *testing.T) {
+	t.Run("hash uses its own settings", func(t *testing.T) {
+		if !Argon2(testDerived).UsesSettings(testSettings) {
+			t.Error("legacy hash should use the test settings")
+		}
+	})
+	t.Run("hash with different settings", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(*Settings)
+		}{
+			{"stronger memory", func(s *Settings) { s.Memory *= 2 }},
+			{"weaker memory", func(s *Settings) { s.Memory /= 2 }},
+			{"time", func(s *Settings) { s.Time++ }},
+			{"threads", func(s *Settings) { s.Threads-- }},
+			{"salt length", func(s *Settings) { s.SaltLength++ }},
+			{"key length", func(s *Settings) { s.KeyLength++ }},
+			{"variant", func(s *Settings) { s.Variant = VariantI }},
+		}
+		argon := Argon2(testDerived)
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				reference := testSettings
+				tt.modify(&reference)
+				if argon.UsesSettings(reference) {
+					t.Errorf("hash with different %s should not use the reference settings", tt.name)
+				}
+			})
+		}
+	})
+	t.Run("keyed hash uses the settings it was derived with", func(t *testing.T) {
+		keyed, err := DeriveWithKey(testPassPhrase, []byte("secret key"), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive keyed hash: %s", err)
+		}
+		if !keyed.UsesSettings(testFastSettings) {
+			t.Error("keyed hash should use the settings it was derived with")
+		}
+	})
+	t.Run("invalid hash does not use any settings", func(t *testing.T) {
+		if Argon2(testDerived[:len(testDerived)-1]).UsesSettings(testSettings) {
+			t.Error("invalid hash should not use any settings")
+		}
+		if Argon2(nil).UsesDefaults() {
+			t.Error("nil hash should not use the default settings")
+		}
+	})
+	t.Run("hash uses the default settings", func(t *testing.T) {
+		salt := make([]byte, DefaultSettings.SaltLength)
+		key := make([]byte, DefaultSettings.KeyLength)
+		if !newHash(DefaultSettings, salt, key).UsesDefaults() {
+			t.Error("hash with default settings should use the default settings")
+		}
+		if Argon2(testDerived).UsesDefaults() {
+			t.Error("hash with test settings should not use the default settings")
+		}
+	})
+}
+
+func TestArgon2_Rehash(t *testing.T) {
+	t.Run("rehash upgrades the settings", func(t *testing.T) {
+		weak, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		target := testFastSettings
+		target.Time = 2
+		if !weak.Validate(testPassPhrase) || !weak.NeedsRehash(target) {
+			t.Fatal("weak hash should be valid and need a rehash")
+		}
+		upgraded, err := weak.Rehash(testPassPhrase, target)
+		if err != nil {
+			t.Fatalf("failed to rehash: %s", err)
+		}
+		if !upgraded.Validate(testPassPhrase) {
+			t.Error("upgraded hash is not valid but should be")
+		}
+		if upgraded.NeedsRehash(target) {
+			t.Error("upgraded hash should not need a rehash")
+		}
+	})
+	t.Run("rehash with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Threads = 0
+		if _, err := Argon2(testDerived).Rehash(testPassPhrase, settings); err == nil {
+			t.Error("rehash with invalid settings should fail")
+		}
+	})
+}
+
+func TestUpgradeHash(t *testing.T) {
+	unversioned := make(Argon2, 0, len(testDerived)+1)
+	unversioned = append(unversioned, testDerived[:LegacySerializedSettingsLength]...)
+	unversioned = append(unversioned, 0x13)
+	unversioned = append(unversioned, testDerived[LegacySerializedSettingsLength:]...)
+
+	t.Run("upgrade older formats", func(t *testing.T) {
+		for name, old := range map[string]Argon2{"legacy": testDerived, "format version 0": unversioned} {
+			upgraded, err := UpgradeHash(old)
+			if err != nil {
+				t.Fatalf("failed to upgrade %s hash: %s", name, err)
+			}
+			if len(upgraded) != SerializedSettingsLength+len(old.Salt())+len(old.Key()) {
+				t.Errorf("upgraded %s hash length is not as expected, got: %d", name, len(upgraded))
+			}
+			if upgraded[0] != SerializedFormatVersion {
+				t.Errorf("upgraded %s hash format version is not as expected, got: %d, want: %d", name,
+					upgraded[0], SerializedFormatVersion)
+			}
+			if !bytes.Equal(upgraded.Salt(), old.Salt()) || !bytes.Equal(upgraded.Key(), old.Key()) {
+				t.Errorf("upgraded %s hash does not contain the original salt and key", name)
+			}
+			if !upgraded.Validate(testPassPhrase) {
+				t.Errorf("upgraded %s hash is not valid but should be", name)
+			}
+		}
+	})
+	t.Run("upgrade current hash returns a copy", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		upgraded, err := UpgradeHash(derived)
+		if err != nil {
+			t.Fatalf("failed to upgrade hash: %s", err)
+		}
+		if !bytes.Equal(upgraded, derived) {
+			t.Errorf("upgraded hash is not as expected, got: %x, want: %x", []byte(upgraded), []byte(derived))
+		}
+		upgraded[len(upgraded)-1] ^= 0xff
+		if bytes.Equal(upgraded, derived) {
+			t.Error("upgraded hash should not share its backing array with the original")
+		}
+	})
+	t.Run("upgrade invalid hash fails", func(t *testing.T) {
+		if _, err := UpgradeHash(testDerived[:len(testDerived)-3]); !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("upgrading invalid hash should fail with invalid hash length, got: %v", err)
+		}
+	})
+}
+
+func TestArgon2_ValidateAndCheck(t *testing.T) {
+	t.Run("valid password with matching settings", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		valid, needsRehash := argon.ValidateAndCheck(testPassPhrase, testSettings)
+		if !valid {
+			t.Error("hash is not valid but should be")
+		}
+		if needsRehash {
+			t.Error("hash matching the target settings should not need a rehash")
+		}
+	})
+	t.Run("valid password with stronger target settings", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		target := testSettings
+		target.Time++
+		valid, needsRehash := argon.ValidateAndCheck(testPassPhrase, target)
+		if !valid {
+			t.Error("hash is not valid but should be")
+		}
+		if !needsRehash {
+			t.Error("hash with weaker settings should need a rehash")
+		}
+	})
+	t.Run("invalid password never reports a rehash", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		target := testSettings
+		target.Time++
+		valid, needsRehash := argon.ValidateAndCheck("invalid", target)
+		if valid {
+			t.Error("hash is valid with wrong password")
+		}
+		if needsRehash {
+			t.Error("invalid password should not report a rehash")
+		}
+	})
+	t.Run("nil hash is not valid", func(t *testing.T) {
+		var argon Argon2
+		valid, needsRehash := argon.ValidateAndCheck(testPassPhrase, testSettings)
+		if valid || needsRehash {
+			t.Errorf("nil hash should be invalid without rehash, got: %t/%t", valid, needsRehash)
+		}
+	})
+}
+
+func BenchmarkDerive(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = Derive(testPassPhrase, DefaultSettings)
+	}
+}
+
+func BenchmarkArgon2_Validate(b *testing.B) {
+	derived, err := Derive(testPassPhrase, testFastSettings)
+	if err != nil {
+		b.Fatalf("failed to derive hash: %s", err)
+	}
+	b.Run("valid hash", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = derived.Validate(testPassPhrase)
+		}
+	})
+	b.Run("invalid hash", func(b *testing.B) {
+		truncated := derived[:len(derived)-2]
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = truncated.Validate(testPassPhrase)
+		}
+	})
+}
+
+func FuzzArgon2_DeclaredLengths(f *testing.F) {
+	f.Add(uint32(16), uint32(32), uint16(48))
+	f.Add(uint32(16), uint32(32), uint16(47))
+	f.Add(uint32(1<<20), uint32(32), uint16(48))
+	f.Add(uint32(math.MaxUint32), uint32(math.MaxUint32), uint16(0))
+	f.Add(uint32(math.MaxUint32), uint32(1), uint16(0))
+	f.Add(uint32(0), uint32(0), uint16(1))
+	f.Fuzz(func(t *testing.T, saltLength, keyLength uint32, available uint16) {
+		settings := testFastSettings
+		settings.SaltLength = saltLength
+		settings.KeyLength = keyLength
+		argon := Argon2(append(settings.Serialize(), make([]byte, available)...))
+
+		fits := uint64(saltLength)+uint64(keyLength) == uint64(available)
+		salt, key := argon.Salt(), argon.Key()
+		saltE, saltErr := argon.SaltE()
+		keyE, keyErr := argon.KeyE()
+		if !fits {
+			if len(salt) != 0 || len(key) != 0 {
+				t.Fatalf("overrunning header should return empty salt and key, got: %d, %d", len(salt),
+					len(key))
+			}
+			if !errors.Is(saltErr, ErrInvalidHashLength) || !errors.Is(keyErr, ErrInvalidHashLength) {
+				t.Fatalf("overrunning header should fail with ErrInvalidHashLength, got: %v, %v", saltErr,
+					keyErr)
+			}
+			return
+		}
+		if saltErr != nil || keyErr != nil {
+			t.Fatalf("extracting salt and key of a matching header failed: %v, %v", saltErr, keyErr)
+		}
+		if len(salt) != int(saltLength) || len(key) != int(keyLength) || len(saltE) != len(salt) ||
+			len(keyE) != len(key) {
+			t.Fatalf("salt and key lengths do not match the header, got: %d, %d", len(salt), len(key))
+		}
+	})
+}
+
+func FuzzArgon2(f *testing.F) {
+	f.Add(testDerived)
+	f.Add(Argon2(testDerived).Salt())
+	f.Add([]byte{})
+	f.Add(make([]byte, LegacySerializedSettingsLength))
+	f.Add(make([]byte, SerializedSettingsLength))
+	f.Add(append(testFastSettings.Serialize(), make([]byte, 48)...))
+	f.Add([]byte{
+		0x40, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0x13,
+	})
+
+	// Invalid hashes are validated with the DefaultSettings, which are too expensive for fuzzing.
+	defaultSettings := DefaultSettings
+	DefaultSettings = testFastSettings
+	f.Cleanup(func() { DefaultSettings = defaultSettings })
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		argon := Argon2(data)
+		layout, ok := parseLayout(data)
+		salt, key := argon.Salt(), argon.Key()
+		if !ok {
+			if len(salt) != 0 || len(key) != 0 {
+				t.Fatalf("invalid hash should return empty salt and key, got: %x, %x", salt, key)
+			}
+			return
+		}
+		if len(salt) != int(layout.settings.SaltLength) || len(key) != int(layout.settings.KeyLength) {
+			t.Fatalf("salt and key lengths do not match the settings, got: %d, %d", len(salt), len(key))
+		}
+
+		// Only validate hashes with settings that are cheap to derive, since the stored settings
+		// are used for the KDF.
+		settings := layout.settings
+		if settings.Memory > 1024 || settings.Time > 4 || settings.Threads > 4 ||
+			settings.SaltLength > 1024 || settings.KeyLength > 1024 {
+			return
+		}
+		if argon.Validate(testPassPhrase) {
+			t.Fatal("fuzzed hash should not be valid")
+		}
+	})
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (n int, err error) {
+	clear(p)
+	return len(p), nil
 }
 
 type failReader struct{}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package argon2test provides helpers for creating cheap Argon2 fixture hashes in tests.
+//
+// Hashes that are derived with the secure settings of the argon2 package allocate up to 1 GiB of
+// memory each, which slows down test suites that only need valid hashes to work with. The helpers
+// of this package derive real, fully valid hashes with FastTestSettings instead. They must never
+// be used outside of tests.
+package argon2test
+
+import (
+	"testing"
+
+	"github.com/wneessen/argon2"
+	xargon2 "golang.org/x/crypto/argon2"
+)
+
+// FastTestSettings are minimal Argon2 settings for fixture hashes in tests.
+//
+// The settings use the smallest time cost that is accepted by Settings.Validate and a memory cost
+// of 64 KiB, which is above the minimum of argon2.MinMemoryPerThread per thread, but still small
+// enough that a single derivation takes only microseconds. They are INSECURE and must only be
+// used in tests, never for storing real passwords.
+//
+// The settings are as follows:
+//   - Memory: 64 KiB
+//   - Time: 1 iteration
+//   - Threads: 1 thread
+//   - SaltLength: 16 bytes for the salt
+//   - KeyLength: 32 bytes for the derived key
+var FastTestSettings = argon2.Settings{
+	Memory:     64,
+	Time:       1,
+	Threads:    1,
+	SaltLength: 16,
+	KeyLength:  32,
+	Variant:    argon2.VariantID,
+	Version:    xargon2.Version,
+}
+
+// TestHash derives an Argon2 hash of the given password with FastTestSettings.
+//
+// The returned hash is a real hash that validates the password with all validation methods of
+// the argon2 package. If the hash cannot be derived, the test fails immediately.
+//
+// Parameters:
+//   - tb: The test or benchmark that the hash is created for.
+//   - password: The password to derive the hash from.
+//
+// Returns:
+//   - The Argon2 hash of the password.
+func TestHash(tb testing.TB, password string) argon2.Argon2 {
+	tb.Helper()
+	hash, err := argon2.Derive(password, FastTestSettings)
+	if err != nil {
+		tb.Fatalf("failed to derive test hash: %s", err)
+	}
+	return hash
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2test
+
+import (
+	"testing"
+)
+
+func TestFastTestSettings(t *testing.T) {
+	t.Run("fast test settings are valid", func(t *testing.T) {
+		if err := FastTestSettings.Validate(); err != nil {
+			t.Errorf("fast test settings should be valid, got: %s", err)
+		}
+	})
+}
+
+func TestTestHash(t *testing.T) {
+	t.Run("test hash validates the password", func(t *testing.T) {
+		hash := TestHash(t, "password")
+		if !hash.Validate("password") {
+			t.Error("test hash is not valid but should be")
+		}
+		if hash.Validate("invalid") {
+			t.Error("test hash should not be valid with a wrong password")
+		}
+	})
+	t.Run("test hash uses the fast test settings", func(t *testing.T) {
+		if !TestHash(t, "password").UsesSettings(FastTestSettings) {
+			t.Error("test hash should use the fast test settings")
+		}
+	})
+}
+
+func BenchmarkTestHash(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		TestHash(b, "password")
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// DeriveWithAssociatedData generates an Argon2 hash that is bound to the given associated data,
+// e.g. a user ID or a tenant.
+//
+// The Argon2 specification supports an optional associated data input, but golang.org/x/crypto/argon2
+// does not expose it. Instead, the associated data is prepended to the random salt in a
+// length-prefixed way before it is passed to the Argon2 KDF:
+//
+//	uint32 little-endian length of data || data || random salt
+//
+// The length prefix ensures that different combinations of associated data and salt can never
+// result in the same KDF input. Only the random salt is stored in the hash; the associated data
+// is not. A hash created with associated data can only be validated with
+// ValidateWithAssociatedData using the same associated data, so it cannot be replayed across
+// accounts. Empty associated data is equivalent to no associated data, so the hash is the same
+// as one created by Derive.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - data: The associated data that the hash is bound to.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if any issues occur during the hash generation.
+func DeriveWithAssociatedData(password string, data []byte, settings Settings) (Argon2, error) {
+	return derive([]byte(password), data, options{settings: settings}, rand.Reader)
+}
+
+// ValidateWithAssociatedData verifies whether the given password matches an Argon2 hash that
+// was created with DeriveWithAssociatedData using the given associated data.
+//
+// The associated data is bound to the stored salt in the same way as in DeriveWithAssociatedData.
+// All timing attack mitigations of Validate apply.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//   - data: The associated data that the hash was bound to.
+//
+// Returns:
+//   - true if the password is valid and matches the stored Argon2 hash for the given associated data.
+func (a Argon2) ValidateWithAssociatedData(password string, data []byte) bool {
+	_, err := a.validate([]byte(password), data)
+	return err == nil
+}
+
+// bindAssociatedData prepends the length-prefixed associated data to the salt. If the associated
+// data is empty, the salt is returned unchanged.
+func bindAssociatedData(salt, data []byte) []byte {
+	if len(data) == 0 {
+		return salt
+	}
+	bound := make([]byte, 4+len(data)+len(salt))
+	binary.LittleEndian.PutUint32(bound[0:4], uint32(len(data)))
+	copy(bound[4:], data)
+	copy(bound[4+len(data):], salt)
+	return bound
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeriveWithAssociatedData(t *testing.T) {
+	t.Run("derive with associated data succeeds", func(t *testing.T) {
+		derived, err := DeriveWithAssociatedData(testPassPhrase, []byte("user-1"), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with associated data: %s", err)
+		}
+		if !derived.ValidateWithAssociatedData(testPassPhrase, []byte("user-1")) {
+			t.Error("derived hash is not valid with the same associated data")
+		}
+	})
+	t.Run("derive with empty associated data equals derive", func(t *testing.T) {
+		derived, err := DeriveWithAssociatedData(testPassPhrase, nil, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with associated data: %s", err)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Error("derived hash with empty associated data is not valid without associated data")
+		}
+	})
+	t.Run("derive with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Time = 0
+		if _, err := DeriveWithAssociatedData(testPassPhrase, []byte("user-1"), settings); err == nil {
+			t.Fatal("derive with invalid settings should have failed")
+		}
+	})
+}
+
+func TestArgon2_ValidateWithAssociatedData(t *testing.T) {
+	derived, err := DeriveWithAssociatedData(testPassPhrase, []byte("user-1"), testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash with associated data: %s", err)
+	}
+	t.Run("validate with different associated data fails", func(t *testing.T) {
+		if derived.ValidateWithAssociatedData(testPassPhrase, []byte("user-2")) {
+			t.Error("hash is valid with different associated data")
+		}
+	})
+	t.Run("validate without associated data fails", func(t *testing.T) {
+		if derived.Validate(testPassPhrase) {
+			t.Error("hash is valid without associated data")
+		}
+	})
+	t.Run("validate with wrong password fails", func(t *testing.T) {
+		if derived.ValidateWithAssociatedData("invalid", []byte("user-1")) {
+			t.Error("hash is valid with wrong password")
+		}
+	})
+}
+
+func TestBindAssociatedData(t *testing.T) {
+	t.Run("bind is length-prefixed", func(t *testing.T) {
+		salt := []byte("salt")
+		want := []byte{0x02, 0x00, 0x00, 0x00, 'a', 'd', 's', 'a', 'l', 't'}
+		if got := bindAssociatedData(salt, []byte("ad")); !bytes.Equal(got, want) {
+			t.Errorf("bound salt is not as expected, got: %x, want: %x", got, want)
+		}
+	})
+	t.Run("bind with empty data returns salt", func(t *testing.T) {
+		salt := []byte("salt")
+		if got := bindAssociatedData(salt, nil); !bytes.Equal(got, salt) {
+			t.Errorf("bound salt is not as expected, got: %x, want: %x", got, salt)
+		}
+	})
+}
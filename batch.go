@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// DeriveBatch generates an Argon2 hash for each of the provided passwords using the given
+// settings, fanning the derivations out across a pool of concurrent workers.
+//
+// This is meant for bulk operations like importing or seeding a user database, where deriving
+// the hashes one after another would leave most of the CPU cores idle. The order of the returned
+// hashes matches the order of the provided passwords.
+//
+// Every single Argon2 derivation already runs settings.Threads goroutines internally, and each of
+// them holds settings.Memory KiB of memory while running. The effective parallelism is therefore
+// concurrency * settings.Threads, and the peak memory usage is concurrency * settings.Memory KiB.
+// To avoid oversubscribing the CPU, concurrency should be chosen so that concurrency *
+// settings.Threads does not exceed the number of available CPU cores. If concurrency is less than
+// 1, it defaults to GOMAXPROCS divided by settings.Threads, with a minimum of 1.
+//
+// Parameters:
+//   - passwords: The passwords to derive the hashes from.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//   - concurrency: The number of hashes that are derived concurrently.
+//
+// Returns:
+//   - A slice of Argon2 hashes in the same order as the provided passwords.
+//   - An error if the settings are invalid or if any of the derivations fails. In that case no
+//     hashes are returned and the remaining derivations are skipped.
+func DeriveBatch(passwords []string, settings Settings, concurrency int) ([]Argon2, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	if concurrency < 1 {
+		concurrency = max(1, runtime.GOMAXPROCS(0)/int(settings.Threads))
+	}
+	concurrency = min(concurrency, len(passwords))
+
+	hashes := make([]Argon2, len(passwords))
+	jobs := make(chan int)
+	done := make(chan struct{})
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hash, err := Derive(passwords[i], settings)
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("failed to derive hash for password at index %d: %w", i, err)
+						close(done)
+					})
+					continue
+				}
+				hashes[i] = hash
+			}
+		}()
+	}
+
+dispatch:
+	for i := range passwords {
+		select {
+		case jobs <- i:
+		case <-done:
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return hashes, nil
+}
+
+// DeriveAll reads newline-delimited passwords from the provided io.Reader and generates an
+// Argon2 hash for each of them using the given settings.
+//
+// This is meant for bulk operations like a batch password reset, where the passwords are
+// provided in a file with one password per line. Both LF and CRLF line endings are supported,
+// and the line ending is not part of the password. All other characters, including leading and
+// trailing whitespace, are part of the password. The hashes are derived one after another; use
+// DeriveBatch to derive the hashes concurrently.
+//
+// By default, an empty line results in an error wrapping ErrEmptyPassword. Use WithSkipEmptyLines to skip empty lines
+// instead. All other options, e.g. WithUnicodeNormalization, are applied as with
+// DeriveWithOptions, with the given settings as base.
+//
+// Parameters:
+//   - r: The io.Reader to read the newline-delimited passwords from.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//   - opts: A list of Option values that configure the derivation.
+//
+// Returns:
+//   - A slice of Argon2 hashes in the same order as the passwords in the input.
+//   - An error if reading fails, if a line is empty and empty lines are not skipped, or if any
+//     of the derivations fails. The error contains the number of the offending line. In that
+//     case no hashes are returned.
+func DeriveAll(r io.Reader, settings Settings, opts ...Option) ([]Argon2, error) {
+	o := newOptions(settings, opts...)
+	scanner := bufio.NewScanner(r)
+	var hashes []Argon2
+	for line := 1; scanner.Scan(); line++ {
+		password := scanner.Bytes()
+		if len(password) == 0 {
+			if o.skipEmpty {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, ErrEmptyPassword)
+		}
+		hash, err := derive(password, nil, o, rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read passwords: %w", err)
+	}
+	return hashes, nil
+}
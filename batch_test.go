@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDeriveBatch(t *testing.T) {
+	t.Run("derive batch preserves order", func(t *testing.T) {
+		passwords := make([]string, 20)
+		for i := range passwords {
+			passwords[i] = fmt.Sprintf("password-%d", i)
+		}
+		hashes, err := DeriveBatch(passwords, testFastSettings, 4)
+		if err != nil {
+			t.Fatalf("failed to derive batch: %s", err)
+		}
+		if len(hashes) != len(passwords) {
+			t.Fatalf("unexpected number of hashes, got: %d, want: %d", len(hashes), len(passwords))
+		}
+		for i, hash := range hashes {
+			if !hash.Validate(passwords[i]) {
+				t.Errorf("hash at index %d does not match password %q", i, passwords[i])
+			}
+		}
+	})
+	t.Run("derive batch with default concurrency", func(t *testing.T) {
+		passwords := []string{"one", "two", "three"}
+		hashes, err := DeriveBatch(passwords, testFastSettings, 0)
+		if err != nil {
+			t.Fatalf("failed to derive batch: %s", err)
+		}
+		for i, hash := range hashes {
+			if !hash.Validate(passwords[i]) {
+				t.Errorf("hash at index %d does not match password %q", i, passwords[i])
+			}
+		}
+	})
+	t.Run("derive batch with concurrency larger than batch", func(t *testing.T) {
+		hashes, err := DeriveBatch([]string{testPassPhrase}, testFastSettings, 16)
+		if err != nil {
+			t.Fatalf("failed to derive batch: %s", err)
+		}
+		if len(hashes) != 1 || !hashes[0].Validate(testPassPhrase) {
+			t.Error("derived batch hash is not valid")
+		}
+	})
+	t.Run("derive empty batch", func(t *testing.T) {
+		hashes, err := DeriveBatch(nil, testFastSettings, 4)
+		if err != nil {
+			t.Fatalf("failed to derive empty batch: %s", err)
+		}
+		if len(hashes) != 0 {
+			t.Errorf("expected no hashes, got: %d", len(hashes))
+		}
+	})
+	t.Run("derive batch with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Threads = 0
+		if _, err := DeriveBatch([]string{testPassPhrase}, settings, 4); err == nil {
+			t.Error("deriving batch with invalid settings should fail")
+		}
+	})
+	t.Run("derive batch with unsupported version fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Version = 0x10
+		if _, err := DeriveBatch([]string{"one", "two", "three"}, settings, 2); err == nil {
+			t.Error("deriving batch with unsupported version should fail")
+		}
+	})
+}
+
+func TestDeriveAll(t *testing.T) {
+	t.Run("derive all preserves order and handles CRLF", func(t *testing.T) {
+		hashes, err := DeriveAll(strings.NewReader("one\r\ntwo\n three \r\nfour"), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hashes: %s", err)
+		}
+		passwords := []string{"one", "two", " three ", "four"}
+		if len(hashes) != len(passwords) {
+			t.Fatalf("unexpected number of hashes, got: %d, want: %d", len(hashes), len(passwords))
+		}
+		for i, password := range passwords {
+			if !hashes[i].Validate(password) {
+				t.Errorf("hash %d is not valid for password %q", i, password)
+			}
+		}
+	})
+	t.Run("empty line fails by default", func(t *testing.T) {
+		_, err := DeriveAll(strings.NewReader("one\n\ntwo\n"), testFastSettings)
+		if !errors.Is(err, ErrEmptyPassword) || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("derive all should have failed on line 2 with ErrEmptyPassword, got: %v", err)
+		}
+	})
+	t.Run("empty lines are skipped when enabled", func(t *testing.T) {
+		hashes, err := DeriveAll(strings.NewReader("one\r\n\r\ntwo\n"), testFastSettings,
+			WithSkipEmptyLines(true))
+		if err != nil {
+			t.Fatalf("failed to derive hashes: %s", err)
+		}
+		if len(hashes) != 2 || !hashes[1].Validate("two") {
+			t.Errorf("empty line should have been skipped, got %d hashes", len(hashes))
+		}
+	})
+	t.Run("empty input returns no hashes", func(t *testing.T) {
+		hashes, err := DeriveAll(strings.NewReader(""), testFastSettings)
+		if err != nil || len(hashes) != 0 {
+			t.Errorf("empty input should return no hashes, got: %d, %v", len(hashes), err)
+		}
+	})
+	t.Run("derive all with broken reader fails", func(t *testing.T) {
+		if _, err := DeriveAll(failReader{}, testFastSettings); err == nil {
+			t.Error("derive all from broken reader should have failed")
+		}
+	})
+	t.Run("derive all with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Time = 0
+		if _, err := DeriveAll(strings.NewReader("one\n"), settings); err == nil {
+			t.Error("derive all with invalid settings should have failed")
+		}
+	})
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptPrefixes are the prefixes of the bcrypt hash versions that are detected by
+// VerifyAndUpgrade.
+var bcryptPrefixes = [][]byte{[]byte("$2a$"), []byte("$2b$"), []byte("$2y$")}
+
+// GenerateFromPassword returns the Argon2 hash of the password with the given settings.
+//
+// This function mirrors the signature of GenerateFromPassword of golang.org/x/crypto/bcrypt
+// to ease the migration from bcrypt. The Settings take the place of the bcrypt cost: instead of
+// a single work factor, they define the memory, time and parallelism of the derivation. Use
+// DefaultSettings or one of the settings profiles if unsure. Internally it wraps DeriveBytes
+// and returns the hash in the native byte layout of this package, which can be compared with
+// CompareHashAndPassword.
+//
+// Parameters:
+//   - password: The password to derive the hash from.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the settings are invalid or if any issues occur during the hash generation.
+func GenerateFromPassword(password []byte, settings Settings) ([]byte, error) {
+	return DeriveBytes(password, settings)
+}
+
+// CompareHashAndPassword compares an Argon2 hash with its possible plaintext equivalent.
+//
+// This function mirrors the signature of CompareHashAndPassword of golang.org/x/crypto/bcrypt
+// to ease the migration from bcrypt. The hashedPassword is treated as an Argon2 hash in the
+// native byte layout of this package. It behaves exactly like VerifyPassword, including all
+// timing attack mitigations.
+//
+// Parameters:
+//   - hashedPassword: The Argon2 hash to compare against.
+//   - password: The plaintext password to compare.
+//
+// Returns:
+//   - nil if the password matches the hash.
+//   - ErrMismatchedHashAndPassword if the hash is valid but does not match the password.
+//   - An error wrapping ErrInvalidHash if the hash is structurally invalid or cannot be validated.
+func CompareHashAndPassword(hashedPassword, password []byte) error {
+	_, err := Argon2(hashedPassword).validate(password, nil)
+	return err
+}
+
+// VerifyAndUpgrade verifies the password against a stored bcrypt or Argon2 hash and returns a
+// freshly derived Argon2 hash if the stored hash should be replaced.
+//
+// This function powers a transparent migration from bcrypt to Argon2 on login. If the stored
+// hash starts with a bcrypt prefix ("$2a$", "$2b$" or "$2y$"), the password is verified using
+// golang.org/x/crypto/bcrypt, which is part of the golang.org/x/crypto module that this package
+// already depends on. On success, the password is hashed with Argon2 using the target settings.
+// Any other stored hash is treated as an Argon2 hash in the native byte layout of this package
+// and validated with all timing attack mitigations of VerifyPassword. If it is valid but
+// NeedsRehash reports that it is weaker than the target settings, it is re-derived as well.
+//
+// Note that the verification of a bcrypt hash is not protected by the dummy hash mechanism of
+// this package, so the timing can tell a bcrypt hash apart from an Argon2 hash.
+//
+// Parameters:
+//   - stored: The stored bcrypt or Argon2 hash.
+//   - password: The plaintext password to verify.
+//   - target: The Settings to derive the upgraded Argon2 hash with.
+//
+// Returns:
+//   - ok: true if the password matches the stored hash.
+//   - upgraded: The newly derived Argon2 hash that should be stored instead of the stored hash, or
+//     nil if the password does not match or the stored hash does not need to be replaced.
+//   - err: An error if the stored hash is invalid, or if the password matches but the upgraded
+//     hash cannot be derived. A wrong password is not reported as an error.
+func VerifyAndUpgrade(stored []byte, password string, target Settings) (ok bool, upgraded Argon2, err error) {
+	if isBcryptHash(stored) {
+		err = bcrypt.CompareHashAndPassword(stored, []byte(password))
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil, nil
+		case err != nil:
+			return false, nil, fmt.Errorf("failed to verify bcrypt hash: %w", err)
+		}
+		upgraded, err = Derive(password, target)
+		return true, upgraded, err
+	}
+
+	layout, err := Argon2(stored).validate([]byte(password), nil)
+	switch {
+	case errors.Is(err, ErrMismatchedHashAndPassword):
+		return false, nil, nil
+	case err != nil:
+		return false, nil, err
+	case !layout.settings.weakerThan(target):
+		return true, nil, nil
+	}
+	upgraded, err = Derive(password, target)
+	return true, upgraded, err
+}
+
+// isBcryptHash reports whether the given hash starts with one of the bcryptPrefixes.
+func isBcryptHash(hash []byte) bool {
+	for _, prefix := range bcryptPrefixes {
+		if bytes.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGenerateFromPassword(t *testing.T) {
+	t.Run("generate and compare", func(t *testing.T) {
+		hash, err := GenerateFromPassword([]byte(testPassPhrase), testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to generate hash: %s", err)
+		}
+		if err = CompareHashAndPassword(hash, []byte(testPassPhrase)); err != nil {
+			t.Errorf("comparing generated hash should succeed, got: %s", err)
+		}
+	})
+	t.Run("generate with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Time = 0
+		if _, err := GenerateFromPassword([]byte(testPassPhrase), settings); err == nil {
+			t.Error("generating hash with invalid settings should fail")
+		}
+	})
+}
+
+func TestCompareHashAndPassword(t *testing.T) {
+	hash, err := DeriveBytes([]byte(testPassPhrase), testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash: %s", err)
+	}
+	t.Run("compare with matching password", func(t *testing.T) {
+		if err := CompareHashAndPassword(hash, []byte(testPassPhrase)); err != nil {
+			t.Errorf("comparing with matching password should succeed, got: %s", err)
+		}
+	})
+	t.Run("compare with wrong password fails", func(t *testing.T) {
+		err := CompareHashAndPassword(hash, []byte("wrong password"))
+		if !errors.Is(err, ErrMismatchedHashAndPassword) {
+			t.Errorf("comparing with wrong password should fail with mismatch, got: %v", err)
+		}
+	})
+	t.Run("compare with invalid hash fails", func(t *testing.T) {
+		err := CompareHashAndPassword(hash[:len(hash)-2], []byte(testPassPhrase))
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("comparing with invalid hash should fail with invalid hash, got: %v", err)
+		}
+	})
+}
+
+func TestVerifyAndUpgrade(t *testing.T) {
+	stored, err := bcrypt.GenerateFromPassword([]byte(testPassPhrase), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to generate bcrypt hash: %s", err)
+	}
+	t.Run("bcrypt hash is upgraded", func(t *testing.T) {
+		for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+			hash := append([]byte(prefix), stored[4:]...)
+			ok, upgraded, err := VerifyAndUpgrade(hash, testPassPhrase, testFastSettings)
+			if err != nil {
+				t.Fatalf("failed to verify %s bcrypt hash: %s", prefix, err)
+			}
+			if !ok {
+				t.Fatalf("password should match the %s bcrypt hash", prefix)
+			}
+			if !upgraded.Validate(testPassPhrase) {
+				t.Errorf("upgraded hash of %s bcrypt hash is not valid", prefix)
+			}
+			if !upgraded.UsesSettings(testFastSettings) {
+				t.Errorf("upgraded hash should use the target settings")
+			}
+		}
+	})
+	t.Run("bcrypt hash with wrong password", func(t *testing.T) {
+		ok, upgraded, err := VerifyAndUpgrade(stored, "invalid", testFastSettings)
+		if err != nil {
+			t.Fatalf("wrong password should not return an error, got: %s", err)
+		}
+		if ok || upgraded != nil {
+			t.Error("wrong password should not match the bcrypt hash")
+		}
+	})
+	t.Run("invalid bcrypt hash fails", func(t *testing.T) {
+		if _, _, err := VerifyAndUpgrade([]byte("$2a$invalid"), testPassPhrase, testFastSettings); err == nil {
+			t.Error("verification of invalid bcrypt hash should fail")
+		}
+	})
+	t.Run("bcrypt hash with invalid target fails", func(t *testing.T) {
+		target := testFastSettings
+		target.Threads = 0
+		ok, _, err := VerifyAndUpgrade(stored, testPassPhrase, target)
+		if err == nil {
+			t.Fatal("upgrade with invalid target settings should fail")
+		}
+		if !ok {
+			t.Error("password should still be reported as matching")
+		}
+	})
+	t.Run("argon2 hash matching the target is not upgraded", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		ok, upgraded, err := VerifyAndUpgrade(derived, testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to verify argon2 hash: %s", err)
+		}
+		if !ok || upgraded != nil {
+			t.Errorf("argon2 hash should match without an upgrade, got: %t, %x", ok, []byte(upgraded))
+		}
+	})
+	t.Run("weaker argon2 hash is upgraded", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		target := testFastSettings
+		target.Time = 2
+		ok, upgraded, err := VerifyAndUpgrade(derived, testPassPhrase, target)
+		if err != nil {
+			t.Fatalf("failed to verify argon2 hash: %s", err)
+		}
+		if !ok || !upgraded.UsesSettings(target) {
+			t.Error("weaker argon2 hash should be upgraded to the target settings")
+		}
+	})
+	t.Run("argon2 hash with wrong password", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		ok, upgraded, err := VerifyAndUpgrade(derived, "invalid", testFastSettings)
+		if err != nil || ok || upgraded != nil {
+			t.Errorf("wrong password should not match without an error, got: %t, %x, %v", ok, []byte(upgraded), err)
+		}
+	})
+	t.Run("invalid argon2 hash fails", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		_, _, err = VerifyAndUpgrade(derived[:len(derived)-1], testPassPhrase, testFastSettings)
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("verification of invalid argon2 hash should fail with ErrInvalidHash, got: %v", err)
+		}
+	})
+}
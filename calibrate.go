@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxCalibrationTime is the upper bound for the time cost that Calibrate will try before it gives up.
+const maxCalibrationTime = MaxTime
+
+// calibrationPassword is the password that is hashed during the calibration.
+const calibrationPassword = "argon2-calibration-password"
+
+// Calibrate determines Settings for which a single hash derivation takes at least the given
+// target duration on the current hardware.
+//
+// This function starts from DefaultSettings, with the Memory capped at maxMemory, and measures
+// the actual wall-clock time of Derive. As long as the measured duration is below the target,
+// it first doubles the Memory up to maxMemory and then increases the Time, measuring again after
+// every step. This is meant to be used during a startup self-test to size the parameters for the
+// hardware instead of guessing them.
+//
+// Since every step performs a full hash derivation, calibrating for a large target duration or
+// a large maxMemory can take a considerable amount of time and memory.
+//
+// Parameters:
+//   - target: The minimum duration a single hash derivation should take.
+//   - maxMemory: The maximum amount of memory (in KB) that the resulting Settings may use. It is
+//     capped at MaxMemory.
+//
+// Returns:
+//   - The calibrated Settings.
+//   - An error if the starting settings are invalid (e.g. maxMemory is too low), if a derivation
+//     fails, or if the target could not be reached within a reasonable time cost.
+func Calibrate(target time.Duration, maxMemory uint32) (Settings, error) {
+	maxMemory = min(maxMemory, MaxMemory)
+	settings := DefaultSettings
+	if settings.Memory > maxMemory {
+		settings.Memory = maxMemory
+	}
+	if err := settings.Validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid settings for calibration: %w", err)
+	}
+
+	for {
+		start := time.Now()
+		if _, err := Derive(calibrationPassword, settings); err != nil {
+			return Settings{}, fmt.Errorf("failed to derive hash during calibration: %w", err)
+		}
+		if time.Since(start) >= target {
+			return settings, nil
+		}
+
+		switch {
+		case settings.Memory < maxMemory:
+			settings.Memory = uint32(min(uint64(settings.Memory)*2, uint64(maxMemory)))
+		case settings.Time < maxCalibrationTime:
+			settings.Time++
+		default:
+			return Settings{}, fmt.Errorf("failed to reach calibration target of %s with a time cost of %d",
+				target, maxCalibrationTime)
+		}
+	}
+}
+
+// ProbeDuration estimates the duration of a single hash derivation with the Settings on the
+// current hardware.
+//
+// Instead of a full derivation, a single iteration is measured and the result is multiplied by
+// the Time of the Settings, since the duration of Argon2 grows linearly with the number of
+// iterations. The probe still allocates the full Memory of the Settings, so it should not be
+// called on request paths. The estimate is subject to the current load of the machine.
+//
+// Returns:
+//   - The estimated duration of a single hash derivation.
+//   - An error if the settings are invalid or if the probe derivation fails.
+func (s Settings) ProbeDuration() (time.Duration, error) {
+	if err := s.Validate(); err != nil {
+		return 0, err
+	}
+	probe := s
+	probe.Time = 1
+	salt := make([]byte, probe.SaltLength)
+	start := time.Now()
+	if _, err := deriveKey([]byte(calibrationPassword), salt, probe); err != nil {
+		return 0, fmt.Errorf("failed to derive hash during probe: %w", err)
+	}
+	return time.Since(start) * time.Duration(s.Time), nil
+}
+
+// SettingsForBudget scales down the Memory of the baseline Settings, so that the given number of
+// concurrent derivations or validations fit into the given memory budget.
+//
+// Each call of the Argon2 KDF allocates Memory KiB, so the Memory is reduced until
+// Memory*1024*concurrency does not exceed the budget. The Memory is never reduced below the
+// Memory of ProfileInteractive, which is the minimum recommended configuration for password
+// storage, or below MinMemoryPerThread KiB per thread. All other parameters of the baseline are
+// kept. If the baseline already fits into the budget, it is returned unchanged.
+//
+// Parameters:
+//   - memoryBudgetBytes: The memory in bytes that is available for the Argon2 KDF in total.
+//   - concurrency: The number of derivations or validations that may run concurrently.
+//   - baseline: The Settings to scale down.
+//
+// Returns:
+//   - The Settings that fit into the memory budget.
+//   - An error if the concurrency is less than one, if the baseline is invalid, or if even the
+//     minimum Memory does not fit into the budget.
+func SettingsForBudget(memoryBudgetBytes uint64, concurrency int, baseline Settings) (Settings, error) {
+	if concurrency < 1 {
+		return Settings{}, fmt.Errorf("concurrency must be at least 1, got: %d", concurrency)
+	}
+	if err := baseline.Validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid baseline settings: %w", err)
+	}
+
+	available := memoryBudgetBytes / 1024 / uint64(concurrency)
+	if uint64(baseline.Memory) <= available {
+		return baseline, nil
+	}
+	minimum := max(uint64(ProfileInteractive.Memory), MinMemoryPerThread*uint64(baseline.Threads))
+	if available < minimum {
+		return Settings{}, fmt.Errorf("memory budget of %d bytes does not fit %d concurrent calls with the "+
+			"minimum memory of %d KiB", memoryBudgetBytes, concurrency, minimum)
+	}
+	settings := baseline
+	settings.Memory = uint32(available)
+	return settings, nil
+}
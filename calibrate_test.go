@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCalibrate(t *testing.T) {
+	t.Run("calibrate with tiny target returns capped default settings", func(t *testing.T) {
+		settings, err := Calibrate(time.Nanosecond, 64)
+		if err != nil {
+			t.Fatalf("failed to calibrate settings: %s", err)
+		}
+		want := DefaultSettings
+		want.Memory = 64
+		if settings != want {
+			t.Errorf("calibrated settings are not as expected, got: %+v, want: %+v", settings, want)
+		}
+	})
+	t.Run("calibrate increases the cost until the target is reached", func(t *testing.T) {
+		target := 20 * time.Millisecond
+		settings, err := Calibrate(target, 1024)
+		if err != nil {
+			t.Fatalf("failed to calibrate settings: %s", err)
+		}
+		if settings.Memory > 1024 {
+			t.Errorf("calibrated memory exceeds maximum, got: %d, want: <= %d", settings.Memory, 1024)
+		}
+		start := time.Now()
+		if _, err = Derive(testPassPhrase, settings); err != nil {
+			t.Fatalf("failed to derive hash with calibrated settings: %s", err)
+		}
+		if elapsed := time.Since(start); elapsed < target/2 {
+			t.Errorf("derivation with calibrated settings is too fast, got: %s, want: ~%s", elapsed, target)
+		}
+	})
+	t.Run("calibrate fails with too little memory", func(t *testing.T) {
+		_, err := Calibrate(time.Nanosecond, 1)
+		var settingErr *InvalidSettingError
+		if !errors.As(err, &settingErr) {
+			t.Fatalf("calibration should have failed with an InvalidSettingError, got: %v", err)
+		}
+	})
+}
+
+func TestSettings_ProbeDuration(t *testing.T) {
+	t.Run("probe returns a positive duration", func(t *testing.T) {
+		duration, err := testFastSettings.ProbeDuration()
+		if err != nil {
+			t.Fatalf("failed to probe duration: %s", err)
+		}
+		if duration <= 0 {
+			t.Errorf("probed duration should be positive, got: %s", duration)
+		}
+	})
+	t.Run("probe with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Time = 0
+		if _, err := settings.ProbeDuration(); err == nil {
+			t.Error("probe with invalid settings should have failed")
+		}
+	})
+	t.Run("probe with unsupported variant fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Variant = VariantD
+		if _, err := settings.ProbeDuration(); err == nil {
+			t.Error("probe with unsupported variant should have failed")
+		}
+	})
+}
+
+func TestSettingsForBudget(t *testing.T) {
+	t.Run("baseline that fits is returned unchanged", func(t *testing.T) {
+		budget := uint64(ProfileModerate.Memory) * 1024 * 4
+		settings, err := SettingsForBudget(budget, 4, ProfileModerate)
+		if err != nil {
+			t.Fatalf("failed to compute settings for budget: %s", err)
+		}
+		if settings != ProfileModerate {
+			t.Errorf("settings are not as expected, got: %+v, want: %+v", settings, ProfileModerate)
+		}
+	})
+	t.Run("memory is scaled down to the budget", func(t *testing.T) {
+		budget := uint64(512 * 1024 * 1024)
+		settings, err := SettingsForBudget(budget, 16, DefaultSettings)
+		if err != nil {
+			t.Fatalf("failed to compute settings for budget: %s", err)
+		}
+		if settings.Memory != 32*1024 {
+			t.Errorf("memory is not as expected, got: %d, want: %d", settings.Memory, 32*1024)
+		}
+		if settings.EstimatedMemoryBytes(16) > budget {
+			t.Errorf("settings exceed the budget, got: %d, want: <= %d", settings.EstimatedMemoryBytes(16), budget)
+		}
+		want := DefaultSettings
+		want.Memory = settings.Memory
+		if settings != want {
+			t.Errorf("only the memory should be changed, got: %+v, want: %+v", settings, want)
+		}
+	})
+	t.Run("budget below the minimum fails", func(t *testing.T) {
+		budget := uint64(ProfileInteractive.Memory)*1024*8 - 1
+		if _, err := SettingsForBudget(budget, 8, DefaultSettings); err == nil {
+			t.Error("settings for a too small budget should fail")
+		}
+	})
+	t.Run("budget of exactly the minimum succeeds", func(t *testing.T) {
+		budget := uint64(ProfileInteractive.Memory) * 1024 * 8
+		settings, err := SettingsForBudget(budget, 8, DefaultSettings)
+		if err != nil {
+			t.Fatalf("failed to compute settings for budget: %s", err)
+		}
+		if settings.Memory != ProfileInteractive.Memory {
+			t.Errorf("memory is not as expected, got: %d, want: %d", settings.Memory, ProfileInteractive.Memory)
+		}
+	})
+	t.Run("invalid concurrency fails", func(t *testing.T) {
+		if _, err := SettingsForBudget(1<<30, 0, DefaultSettings); err == nil {
+			t.Error("settings for zero concurrency should fail")
+		}
+	})
+	t.Run("invalid baseline fails", func(t *testing.T) {
+		baseline := DefaultSettings
+		baseline.Threads = 0
+		_, err := SettingsForBudget(1<<30, 1, baseline)
+		var settingErr *InvalidSettingError
+		if !errors.As(err, &settingErr) {
+			t.Fatalf("settings for invalid baseline should fail with an InvalidSettingError, got: %v", err)
+		}
+	})
+}
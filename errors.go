@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMismatchedHashAndPassword is returned when a password does not match a structurally
+	// valid Argon2 hash.
+	ErrMismatchedHashAndPassword = errors.New("hashed password is not the hash of the given password")
+
+	// ErrInvalidHash is returned when an Argon2 hash is structurally invalid, e.g. because it was
+	// corrupted or tampered with.
+	ErrInvalidHash = errors.New("invalid Argon2 hash")
+
+	// ErrInvalidHashLength is returned when the length of an Argon2 hash does not match the length
+	// that is described by its serialized settings.
+	ErrInvalidHashLength = errors.New("invalid Argon2 hash length")
+
+	// ErrUnsupportedScanType is returned when Scan is called with a database type that cannot be
+	// converted into an Argon2 hash.
+	ErrUnsupportedScanType = errors.New("unsupported scan type for Argon2")
+
+	// ErrSaltGeneration is returned when the random salt for a new Argon2 hash cannot be generated.
+	ErrSaltGeneration = errors.New("failed to generate random salt")
+
+	// ErrUnsupportedVersion is returned when a hash or settings use an Argon2 version other than
+	// 0x13 (19). golang.org/x/crypto/argon2 only implements version 0x13, so hashes of other
+	// versions, like the legacy version 0x10 (16), cannot be derived or validated.
+	ErrUnsupportedVersion = errors.New("unsupported Argon2 version")
+
+	// ErrUnsupportedVariant is returned when a hash or settings use a Variant that cannot be
+	// derived or validated. This is the case for Argon2d, which is not implemented by
+	// golang.org/x/crypto/argon2, and for unknown variants, e.g. of a corrupted hash.
+	ErrUnsupportedVariant = errors.New("unsupported Argon2 variant")
+
+	// ErrZeroSalt is returned alongside with ErrSaltGeneration when the random source repeatedly
+	// returned a salt that consists of zeros only, which indicates a broken random source.
+	ErrZeroSalt = errors.New("random source returned an all-zero salt")
+
+	// ErrEmptyPassword is returned when an empty password is passed to a derivation that was
+	// configured with WithRejectEmptyPassword.
+	ErrEmptyPassword = errors.New("password must not be empty")
+
+	// ErrPasswordTooLong is returned when a password exceeds MaxPasswordLength.
+	ErrPasswordTooLong = errors.New("password exceeds the maximum length")
+
+	// ErrBusy is returned by a Hasher that was configured with WithConcurrencyLimit when the
+	// maximum number of concurrent derivations and validations is reached.
+	ErrBusy = errors.New("too many concurrent Argon2 operations")
+)
+
+// InvalidSettingError is returned when a field of the Settings is outside the allowed range.
+//
+// Fields:
+//   - Field: The name of the offending field of the Settings struct.
+//   - Value: The value of the offending field.
+//   - Reason: A human-readable description of the violated constraint.
+type InvalidSettingError struct {
+	Field  string
+	Value  uint64
+	Reason string
+}
+
+// Error satisfies the error interface for the InvalidSettingError type.
+func (e *InvalidSettingError) Error() string {
+	return fmt.Sprintf("invalid Argon2 setting %s (%d): %s", e.Field, e.Value, e.Reason)
+}
@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"testing"
+)
+
+func TestInvalidSettingError_Error(t *testing.T) {
+	err := &InvalidSettingError{Field: "Threads", Value: 0, Reason: "must be at least 1"}
+	want := "invalid Argon2 setting Threads (0): must be at least 1"
+	if err.Error() != want {
+		t.Errorf("error message is not as expected, got: %q, want: %q", err.Error(), want)
+	}
+}
@@ -6,6 +6,9 @@ module github.com/wneessen/argon2
 
 go 1.25.0
 
-require golang.org/x/crypto v0.54.0
+require (
+	golang.org/x/crypto v0.54.0
+	golang.org/x/text v0.40.0
+)
 
 require golang.org/x/sys v0.47.0 // indirect
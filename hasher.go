@@ -0,0 +1,349 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Hasher derives and validates Argon2 hashes with a fixed set of Settings.
+//
+// A Hasher centralizes the configuration of the hash generation in a single object, which can
+// be passed to the parts of an application that need to derive or validate hashes, instead of
+// passing the Settings around.
+//
+// Memory reuse: the Argon2 KDF of golang.org/x/crypto/argon2 allocates its full memory matrix
+// of Settings.Memory KiB on every call and does not provide a way to pass in a buffer. A Hasher
+// therefore cannot reuse the memory across derivations, and every Derive and Validate call
+// allocates the configured amount of memory, e.g. 1 GiB with DefaultSettings. See
+// BenchmarkHasher for the allocation cost. The Hasher is the designated place to add buffer
+// reuse, should the underlying package ever support it, without changing the call sites.
+//
+// Since the memory usage grows with every concurrent call, the number of concurrent derivations
+// and validations of a Hasher can be limited with WithConcurrencyLimit, which caps the memory
+// that is used by the Hasher regardless of the request volume.
+//
+// A Hasher is safe for concurrent use by multiple goroutines.
+type Hasher struct {
+	settings   Settings
+	dummy      Argon2
+	onEvent    func(Event)
+	sem        chan struct{}
+	wait       bool
+	strictness Strictness
+	random     io.Reader
+}
+
+// HasherOption represents a functional option that is used to configure a Hasher.
+type HasherOption func(*Hasher)
+
+// EventType identifies the kind of an Event that is emitted by a Hasher.
+type EventType uint8
+
+const (
+	// EventDerive is emitted after a hash was derived with Hasher.Derive.
+	EventDerive EventType = iota
+
+	// EventValidate is emitted after a structurally valid hash was validated with
+	// Hasher.Validate, regardless of whether the password matched.
+	EventValidate
+
+	// EventInvalidHash is emitted after Hasher.Validate was called with a structurally invalid
+	// hash, which might be a sign of tampering.
+	EventInvalidHash
+
+	// EventBusy is emitted when Hasher.Derive or Hasher.Validate was rejected with ErrBusy,
+	// because the concurrency limit of the Hasher was reached.
+	EventBusy
+
+	// EventShortLengths is emitted by NewHasher and Hasher.Validate if the settings of the Hasher
+	// or of a validated hash have a salt or key below RecommendedSaltLength or
+	// RecommendedKeyLength. It is emitted in addition to EventValidate, see WithStrictness.
+	EventShortLengths
+)
+
+// String returns the name of the EventType.
+func (t EventType) String() string {
+	switch t {
+	case EventDerive:
+		return "derive"
+	case EventValidate:
+		return "validate"
+	case EventInvalidHash:
+		return "invalid-hash"
+	case EventBusy:
+		return "busy"
+	case EventShortLengths:
+		return "short-lengths"
+	default:
+		return fmt.Sprintf("EventType(%d)", t)
+	}
+}
+
+// Event describes a derivation or validation that was performed by a Hasher. It is meant for
+// observability, e.g. to detect slow derivations caused by memory pressure. An Event never
+// contains the password, the salt or the derived key.
+//
+// Fields:
+//   - Type: The kind of the event.
+//   - Duration: The wall-clock time the derivation or validation took.
+//   - Settings: The settings that were used for the Argon2 KDF. For EventValidate, these are the
+//     settings stored in the hash. For EventInvalidHash, these are the settings of the dummy
+//     derivation.
+//   - Err: The error of a failed derivation. It is always nil for validations, since a failed
+//     validation is not an error. For EventBusy, it is ErrBusy. For EventShortLengths, it joins
+//     an *InvalidSettingError for every length below the recommended length.
+type Event struct {
+	Type     EventType
+	Duration time.Duration
+	Settings Settings
+	Err      error
+}
+
+// WithOnEvent registers a callback that is called synchronously after every derivation and
+// validation of the Hasher. The callback is called from the goroutine that called Derive or
+// Validate, so it should return quickly and must be safe for concurrent use.
+//
+// Parameters:
+//   - fn: The callback that receives the events. If nil, no events are emitted.
+//
+// Returns:
+//   - A HasherOption that registers the callback.
+func WithOnEvent(fn func(Event)) HasherOption {
+	return func(h *Hasher) {
+		h.onEvent = fn
+	}
+}
+
+// WithConcurrencyLimit limits the number of concurrent derivations and validations of the Hasher.
+//
+// Every call of the Argon2 KDF allocates the full amount of memory of the settings, so N
+// concurrent calls use N times that memory. The limit is enforced with a semaphore, which caps
+// the memory that is used by the Hasher at roughly Settings.EstimatedMemoryBytes(limit). Calls
+// that exceed the limit either block until a slot becomes available or are rejected right away:
+// Derive then fails with ErrBusy and Validate returns false, without executing the Argon2 KDF.
+// Since a rejected Validate cannot be told apart from a wrong password by its result, register
+// a callback with WithOnEvent to observe EventBusy, or let the calls wait.
+//
+// Parameters:
+//   - limit: The maximum number of concurrent derivations and validations. A limit below 1
+//     disables the limit.
+//   - wait: Whether calls that exceed the limit block until a slot becomes available, instead
+//     of being rejected with ErrBusy.
+//
+// Returns:
+//   - A HasherOption that limits the concurrency of the Hasher.
+func WithConcurrencyLimit(limit int, wait bool) HasherOption {
+	return func(h *Hasher) {
+		h.sem = nil
+		if limit > 0 {
+			h.sem = make(chan struct{}, limit)
+		}
+		h.wait = wait
+	}
+}
+
+// WithStrictness sets how the Hasher handles settings with a salt or key below
+// RecommendedSaltLength or RecommendedKeyLength.
+//
+// Such settings are valid, but weaken the hash. In LenientSettings mode, which is the default,
+// they are accepted and reported with an EventShortLengths to the callback that is registered
+// with WithOnEvent. In StrictSettings mode, NewHasher fails for such settings and Validate
+// rejects stored hashes with such settings after executing the Argon2 KDF, so that the rejection
+// takes the same amount of time as any other validation. Since this makes existing hashes with
+// short salts or keys fail to validate, observe the events in lenient mode first.
+//
+// Parameters:
+//   - strictness: Whether short salts and keys are reported or rejected.
+//
+// Returns:
+//   - A HasherOption that sets the strictness of the Hasher.
+func WithStrictness(strictness Strictness) HasherOption {
+	return func(h *Hasher) {
+		h.strictness = strictness
+	}
+}
+
+// WithRandReader sets the random source the Hasher reads the salts of derived hashes and the
+// random values of its dummy hash from.
+//
+// Like with DeriveWithRand, this allows the use of a hardware RNG or another dedicated entropy
+// source, or a deterministic reader in tests. A predictable reader must never be used in
+// production.
+//
+// Parameters:
+//   - random: The io.Reader the random values are read from. If nil, crypto/rand.Reader is used.
+//
+// Returns:
+//   - A HasherOption that sets the random source of the Hasher.
+func WithRandReader(random io.Reader) HasherOption {
+	return func(h *Hasher) {
+		h.random = random
+		if random == nil {
+			h.random = rand.Reader
+		}
+	}
+}
+
+// NewHasher returns a new Hasher that derives hashes with the given settings.
+//
+// The settings are validated once when the Hasher is created, so that a misconfiguration fails
+// fast at startup instead of on the first derivation. The serialized settings and a dummy hash
+// with a random salt and key are computed once as well. Validate uses the dummy hash as fallback
+// for structurally invalid hashes, instead of serializing the settings and generating random
+// values on every call.
+//
+// Parameters:
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//   - opts: A list of HasherOption values that configure the Hasher.
+//
+// Returns:
+//   - A pointer to the new Hasher.
+//   - An error if the settings are invalid, if they have a salt or key below the recommended
+//     length in StrictSettings mode, or if the random values of the dummy hash cannot be
+//     generated.
+func NewHasher(settings Settings, opts ...HasherOption) (*Hasher, error) {
+	hasher := &Hasher{settings: settings, random: rand.Reader}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(hasher)
+	}
+	warnings, err := settings.ValidateWithStrictness(hasher.strictness)
+	if err != nil {
+		return nil, err
+	}
+	hasher.emitShortLengths(settings, warnings)
+	if hasher.dummy, err = newDummyHash(settings, hasher.random); err != nil {
+		return nil, err
+	}
+	return hasher, nil
+}
+
+// Settings returns the Settings the Hasher derives hashes with.
+func (h *Hasher) Settings() Settings {
+	return h.settings
+}
+
+// Derive generates an Argon2 hash from the given password using the settings of the Hasher.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the settings are invalid or if any issues occur during the hash generation.
+//     ErrBusy if the concurrency limit of the Hasher is reached and it does not wait.
+func (h *Hasher) Derive(password string) (Argon2, error) {
+	if !h.acquire() {
+		h.emit(Event{Type: EventBusy, Settings: h.settings, Err: ErrBusy})
+		return nil, ErrBusy
+	}
+	defer h.release()
+
+	start := time.Now()
+	hash, err := DeriveWithRand(password, h.settings, h.random)
+	h.emit(Event{Type: EventDerive, Duration: time.Since(start), Settings: h.settings, Err: err})
+	return hash, err
+}
+
+// Validate verifies whether the given password matches the given Argon2 hash.
+//
+// The hash is validated with the settings that are stored in the hash, not with the settings
+// of the Hasher, so hashes that were derived with older settings can still be validated. It
+// behaves like Argon2.Validate, including all timing attack mitigations, but uses the settings
+// of the Hasher instead of DefaultSettings for the dummy derivation of invalid hashes, so that
+// the timing matches the validation of hashes derived by the Hasher.
+//
+// Parameters:
+//   - hash: The Argon2 hash to validate the password against.
+//   - password: The plaintext password to validate.
+//
+// Returns:
+//   - true if the password is valid and matches the Argon2 hash. It is false if the call was
+//     rejected, because the concurrency limit of the Hasher is reached and it does not wait, or
+//     if the hash has a salt or key below the recommended length in StrictSettings mode.
+func (h *Hasher) Validate(hash Argon2, password string) bool {
+	if !h.acquire() {
+		h.emit(Event{Type: EventBusy, Settings: h.settings, Err: ErrBusy})
+		return false
+	}
+	defer h.release()
+
+	start := time.Now()
+	layout, err := hash.validateWithDummy([]byte(password), nil, false, h.dummy, MaxPasswordLength)
+	eventType := EventValidate
+	if errors.Is(err, ErrInvalidHash) {
+		eventType = EventInvalidHash
+	}
+	h.emit(Event{Type: eventType, Duration: time.Since(start), Settings: layout.settings})
+	if eventType == EventInvalidHash {
+		return false
+	}
+	warnings := layout.settings.lengthWarnings()
+	h.emitShortLengths(layout.settings, warnings)
+	return err == nil && (h.strictness != StrictSettings || len(warnings) == 0)
+}
+
+// acquire takes a slot of the concurrency limit. It returns false if the limit is reached and
+// the Hasher is not configured to wait for a free slot.
+func (h *Hasher) acquire() bool {
+	if h.sem == nil {
+		return true
+	}
+	if h.wait {
+		h.sem <- struct{}{}
+		return true
+	}
+	select {
+	case h.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees the slot of the concurrency limit that was taken by acquire.
+func (h *Hasher) release() {
+	if h.sem != nil {
+		<-h.sem
+	}
+}
+
+// emit passes the given event to the registered callback, if any.
+func (h *Hasher) emit(event Event) {
+	if h.onEvent != nil {
+		h.onEvent(event)
+	}
+}
+
+// emitShortLengths emits an EventShortLengths for the given settings if there are any warnings
+// about lengths below the recommended lengths.
+func (h *Hasher) emitShortLengths(settings Settings, warnings []*InvalidSettingError) {
+	if len(warnings) == 0 {
+		return
+	}
+	errs := make([]error, len(warnings))
+	for i, warning := range warnings {
+		errs[i] = warning
+	}
+	h.emit(Event{Type: EventShortLengths, Settings: settings, Err: errors.Join(errs...)})
+}
+
+// newDummyHash returns a hash with the given settings and a salt and key that are read from the
+// given random source, which is used as a fallback for the validation of invalid hashes. The
+// caller must ensure that the settings are valid.
+func newDummyHash(settings Settings, reader io.Reader) (Argon2, error) {
+	random := make([]byte, settings.SaltLength+settings.KeyLength)
+	if _, err := io.ReadFull(reader, random); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSaltGeneration, err)
+	}
+	return newHash(settings, random[:settings.SaltLength], random[settings.SaltLength:]), nil
+}
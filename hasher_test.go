@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHasher(t *testing.T) {
+	t.Run("derive and validate", func(t *testing.T) {
+		hasher := newTestHasher(t, testFastSettings)
+		derived, err := hasher.Derive(testPassPhrase)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !hasher.Validate(derived, testPassPhrase) {
+			t.Error("derived hash is not valid but should be")
+		}
+		if hasher.Validate(derived, "wrong password") {
+			t.Error("derived hash should not be valid for a wrong password")
+		}
+		settings, err := derived.Settings()
+		if err != nil {
+			t.Fatalf("failed to extract settings: %s", err)
+		}
+		if !settings.Equal(hasher.Settings()) {
+			t.Errorf("derived settings are not as expected, got: %+v, want: %+v", settings, hasher.Settings())
+		}
+	})
+	t.Run("validate hash with other settings", func(t *testing.T) {
+		hasher := newTestHasher(t, testFastSettings)
+		if !hasher.Validate(testDerived, testPassPhrase) {
+			t.Error("hash with other settings is not valid but should be")
+		}
+	})
+	t.Run("validate invalid hash uses the dummy hash", func(t *testing.T) {
+		hasher := newTestHasher(t, testFastSettings)
+		if hasher.dummy == nil || !hasher.dummy.IsValid() {
+			t.Fatal("hasher should have a valid dummy hash")
+		}
+		for _, hash := range []Argon2{nil, {0x01, 0x02}, make([]byte, SerializedSettingsLength+48)} {
+			if hasher.Validate(hash, testPassPhrase) {
+				t.Errorf("invalid hash %x should not be valid", []byte(hash))
+			}
+		}
+		if hasher.Validate(hasher.dummy, testPassPhrase) {
+			t.Error("dummy hash should not be valid")
+		}
+	})
+	t.Run("hasher with invalid settings fails", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Threads = 0
+		_, err := NewHasher(settings)
+		var settingErr *InvalidSettingError
+		if !errors.As(err, &settingErr) {
+			t.Errorf("new hasher should have failed with an InvalidSettingError, got: %v", err)
+		}
+	})
+	t.Run("hasher with broken reader fails", func(t *testing.T) {
+		if _, err := NewHasher(testFastSettings, WithRandReader(failReader{})); !errors.Is(err, ErrSaltGeneration) {
+			t.Errorf("new hasher should have failed with salt generation error, got: %v", err)
+		}
+	})
+	t.Run("hasher reads salts from random reader", func(t *testing.T) {
+		var hashes []Argon2
+		for range 2 {
+			random := bytes.NewReader(bytes.Repeat([]byte{0x01}, 1024))
+			derived, err := newTestHasher(t, testFastSettings, WithRandReader(random)).Derive(testPassPhrase)
+			if err != nil {
+				t.Fatalf("failed to derive hash: %s", err)
+			}
+			hashes = append(hashes, derived)
+		}
+		if !bytes.Equal(hashes[0], hashes[1]) {
+			t.Error("hashers with the same deterministic reader should derive the same hash")
+		}
+	})
+	t.Run("events are emitted", func(t *testing.T) {
+		var events []Event
+		hasher := newTestHasher(t, testFastSettings, nil, WithOnEvent(func(event Event) {
+			events = append(events, event)
+		}))
+		derived, err := hasher.Derive(testPassPhrase)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		_ = hasher.Validate(derived, testPassPhrase)
+		_ = hasher.Validate(derived, "wrong password")
+		_ = hasher.Validate(nil, testPassPhrase)
+
+		want := []EventType{EventDerive, EventValidate, EventValidate, EventInvalidHash}
+		if len(events) != len(want) {
+			t.Fatalf("unexpected number of events, got: %d, want: %d", len(events), len(want))
+		}
+		for i, event := range events {
+			if event.Type != want[i] {
+				t.Errorf("event %d has unexpected type, got: %s, want: %s", i, event.Type, want[i])
+			}
+			if event.Duration <= 0 {
+				t.Errorf("event %d should have a duration", i)
+			}
+			if !event.Settings.Equal(testFastSettings) {
+				t.Errorf("event %d has unexpected settings, got: %+v", i, event.Settings)
+			}
+			if event.Err != nil {
+				t.Errorf("event %d should not have an error, got: %s", i, event.Err)
+			}
+		}
+	})
+	t.Run("failed derive emits event with error", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Version = 0x10
+		var event Event
+		hasher := newTestHasher(t, settings, WithOnEvent(func(e Event) { event = e }))
+		if _, err := hasher.Derive(testPassPhrase); err == nil {
+			t.Fatal("derive with unsupported version should fail")
+		}
+		if event.Type != EventDerive || !errors.Is(event.Err, ErrUnsupportedVersion) {
+			t.Errorf("unexpected event, got: %+v", event)
+		}
+	})
+}
+
+func TestWithConcurrencyLimit(t *testing.T) {
+	t.Run("calls exceeding the limit are rejected", func(t *testing.T) {
+		var event Event
+		hasher := newTestHasher(t, testFastSettings, WithConcurrencyLimit(1, false),
+			WithOnEvent(func(e Event) { event = e }))
+		derived, err := hasher.Derive(testPassPhrase)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		hasher.acquire()
+		if _, err = hasher.Derive(testPassPhrase); !errors.Is(err, ErrBusy) {
+			t.Errorf("derive should have failed with ErrBusy, got: %v", err)
+		}
+		if event.Type != EventBusy || !errors.Is(event.Err, ErrBusy) {
+			t.Errorf("unexpected event, got: %+v", event)
+		}
+		if hasher.Validate(derived, testPassPhrase) {
+			t.Error("validate should have been rejected")
+		}
+		hasher.release()
+		if !hasher.Validate(derived, testPassPhrase) {
+			t.Error("validate should succeed once a slot is available")
+		}
+	})
+	t.Run("calls exceeding the limit wait for a slot", func(t *testing.T) {
+		hasher := newTestHasher(t, testFastSettings, WithConcurrencyLimit(1, true))
+		hasher.acquire()
+		done := make(chan error, 1)
+		go func() {
+			_, err := hasher.Derive(testPassPhrase)
+			done <- err
+		}()
+		select {
+		case <-done:
+			t.Fatal("derive should wait for a free slot")
+		case <-time.After(50 * time.Millisecond):
+		}
+		hasher.release()
+		if err := <-done; err != nil {
+			t.Errorf("derive should succeed once a slot is available, got: %s", err)
+		}
+	})
+	t.Run("limit below one disables the limit", func(t *testing.T) {
+		hasher := newTestHasher(t, testFastSettings, WithConcurrencyLimit(0, false))
+		if hasher.sem != nil {
+			t.Error("concurrency limit below one should not create a semaphore")
+		}
+		if _, err := hasher.Derive(testPassPhrase); err != nil {
+			t.Errorf("failed to derive hash: %s", err)
+		}
+	})
+}
+
+func TestWithStrictness(t *testing.T) {
+	short := NewSettings(64, 1, 1, 8, 4)
+	derived, err := Derive(testPassPhrase, short)
+	if err != nil {
+		t.Fatalf("failed to derive hash: %s", err)
+	}
+	t.Run("lenient hasher reports short lengths", func(t *testing.T) {
+		var events []Event
+		hasher := newTestHasher(t, short, WithOnEvent(func(event Event) {
+			events = append(events, event)
+		}))
+		if !hasher.Validate(derived, testPassPhrase) {
+			t.Error("lenient hasher should validate hash with short lengths")
+		}
+		want := []EventType{EventShortLengths, EventValidate, EventShortLengths}
+		if len(events) != len(want) {
+			t.Fatalf("unexpected number of events, got: %d, want: %d", len(events), len(want))
+		}
+		for i, event := range events {
+			if event.Type != want[i] {
+				t.Errorf("event %d has unexpected type, got: %s, want: %s", i, event.Type, want[i])
+			}
+		}
+		var settingErr *InvalidSettingError
+		if !errors.As(events[0].Err, &settingErr) || settingErr.Field != "SaltLength" {
+			t.Errorf("short lengths event should contain the salt length warning, got: %v", events[0].Err)
+		}
+	})
+	t.Run("strict hasher rejects short settings", func(t *testing.T) {
+		var settingErr *InvalidSettingError
+		_, err := NewHasher(short, WithStrictness(StrictSettings))
+		if !errors.As(err, &settingErr) || settingErr.Field != "SaltLength" {
+			t.Errorf("new hasher should have failed with short salt length, got: %v", err)
+		}
+	})
+	t.Run("strict hasher rejects short hashes", func(t *testing.T) {
+		var events []Event
+		hasher := newTestHasher(t, testFastSettings, WithStrictness(StrictSettings),
+			WithOnEvent(func(event Event) { events = append(events, event) }))
+		if hasher.Validate(derived, testPassPhrase) {
+			t.Error("strict hasher should not validate hash with short lengths")
+		}
+		if len(events) != 2 || events[1].Type != EventShortLengths {
+			t.Errorf("strict hasher should report short lengths, got: %+v", events)
+		}
+		hash, err := hasher.Derive(testPassPhrase)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !hasher.Validate(hash, testPassPhrase) {
+			t.Error("strict hasher should validate hash with recommended lengths")
+		}
+	})
+}
+
+func TestEventType_String(t *testing.T) {
+	tests := []struct {
+		eventType EventType
+		want      string
+	}{
+		{EventDerive, "derive"},
+		{EventValidate, "validate"},
+		{EventInvalidHash, "invalid-hash"},
+		{EventBusy, "busy"},
+		{EventShortLengths, "short-lengths"},
+		{EventType(99), "EventType(99)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.eventType.String(); got != tt.want {
+				t.Errorf("event type string is not as expected, got: %s, want: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkHasher quantifies the allocation cost of the Argon2 KDF. The allocated bytes per
+// operation are dominated by the memory matrix that golang.org/x/crypto/argon2 allocates on
+// every call, which is roughly equal to the configured memory.
+func BenchmarkHasher(b *testing.B) {
+	for _, settings := range []Settings{testFastSettings, ProfileInteractive} {
+		hasher := newTestHasher(b, settings)
+		derived, err := hasher.Derive(testPassPhrase)
+		if err != nil {
+			b.Fatalf("failed to derive hash: %s", err)
+		}
+		b.Run(fmt.Sprintf("derive %d KiB", settings.Memory), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = hasher.Derive(testPassPhrase)
+			}
+		})
+		b.Run(fmt.Sprintf("validate %d KiB", settings.Memory), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = hasher.Validate(derived, testPassPhrase)
+			}
+		})
+	}
+}
+
+// newTestHasher returns a new Hasher with the given settings and options and fails the test if
+// the Hasher cannot be created.
+func newTestHasher(tb testing.TB, settings Settings, opts ...HasherOption) *Hasher {
+	tb.Helper()
+	hasher, err := NewHasher(settings, opts...)
+	if err != nil {
+		tb.Fatalf("failed to create hasher: %s", err)
+	}
+	return hasher
+}
@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// HashInfo is a summary of the parameters of a stored Argon2 hash.
+//
+// Fields:
+//   - Variant: The Argon2 variant the hash was derived with.
+//   - Version: The Argon2 version the hash was derived with.
+//   - Memory: The memory cost in kilobytes.
+//   - Time: The time cost as number of iterations.
+//   - Threads: The number of parallel threads.
+//   - SaltLength: The length of the salt in bytes.
+//   - KeyLength: The length of the derived key in bytes.
+//   - Weak: Whether the hash was derived with weaker parameters than the threshold, which is
+//     ProfileInteractive for Info.
+type HashInfo struct {
+	Variant    Variant
+	Version    uint8
+	Memory     uint32
+	Time       uint32
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+	Weak       bool
+}
+
+// Info returns a summary of the parameters of the Argon2 hash.
+//
+// This method gives a typed view of a stored hash for reporting, e.g. for an admin dashboard
+// that lists which accounts need a rehash. The Weak field is computed against
+// ProfileInteractive, which is the minimum recommended configuration for password storage,
+// using the same rules as NeedsRehash. Use InfoWithThreshold to apply the policy of the
+// application instead.
+//
+// Returns:
+//   - A HashInfo struct describing the Argon2 hash.
+//   - An error wrapping ErrInvalidHashLength if the hash is structurally invalid.
+func (a Argon2) Info() (HashInfo, error) {
+	return a.InfoWithThreshold(ProfileInteractive)
+}
+
+// InfoWithThreshold returns a summary of the parameters of the Argon2 hash like Info, but
+// computes the Weak field against the given threshold.
+//
+// Parameters:
+//   - threshold: The minimum configuration the hash has to comply with to not be reported as
+//     weak.
+//
+// Returns:
+//   - A HashInfo struct describing the Argon2 hash.
+//   - An error wrapping ErrInvalidHashLength if the hash is structurally invalid.
+func (a Argon2) InfoWithThreshold(threshold Settings) (HashInfo, error) {
+	settings, err := a.Settings()
+	if err != nil {
+		return HashInfo{}, err
+	}
+	return HashInfo{
+		Variant:    settings.Variant,
+		Version:    settings.version(),
+		Memory:     settings.Memory,
+		Time:       settings.Time,
+		Threads:    settings.Threads,
+		SaltLength: settings.SaltLength,
+		KeyLength:  settings.KeyLength,
+		Weak:       settings.weakerThan(threshold),
+	}, nil
+}
+
+// Anonymize returns a loggable description of the parameters of the Argon2 hash that contains
+// neither the salt nor the derived key, e.g. "argon2id[m=131072,t=3,p=4,salt=16B,key=32B]".
+//
+// Unlike String, which returns the full PHC string, the result is safe to include in logs,
+// telemetry or debugging reports. If the Argon2 hash is structurally invalid, "<invalid>" is
+// returned.
+func (a Argon2) Anonymize() string {
+	layout, ok := parseLayout(a)
+	if !ok {
+		return "<invalid>"
+	}
+	settings := layout.settings
+	return fmt.Sprintf("%s[m=%d,t=%d,p=%d,salt=%dB,key=%dB]", settings.Variant, settings.Memory, settings.Time,
+		settings.Threads, settings.SaltLength, settings.KeyLength)
+}
+
+// SettingsDescription summarizes the cost of Settings without executing the Argon2 KDF.
+//
+// Fields:
+//   - HashLength: The length in bytes of a hash derived with the Settings, see HashLength.
+//   - MemoryBytes: The memory in bytes that a single derivation or validation allocates.
+//   - MemoryMiB: The memory of a single derivation or validation in mebibytes.
+//   - Weak: Whether the Settings are weaker than the threshold, which is ProfileInteractive for
+//     Describe.
+//   - ShortLengths: Whether the SaltLength or KeyLength is below RecommendedSaltLength or
+//     RecommendedKeyLength, see ValidateWithStrictness.
+//   - Err: The error returned by Settings.Validate, or nil if the Settings are valid.
+type SettingsDescription struct {
+	HashLength   int
+	MemoryBytes  uint64
+	MemoryMiB    float64
+	Weak         bool
+	ShortLengths bool
+	Err          error
+}
+
+// Describe returns a summary of the cost of the Settings without executing the Argon2 KDF.
+//
+// This method is a deterministic dry run, e.g. for a configuration endpoint of an admin UI that
+// previews the impact of a settings change before it is applied. It does not allocate the
+// configured memory. Use ProbeDuration to additionally measure the expected duration on the
+// current hardware. The Weak field is computed against ProfileInteractive, use
+// DescribeWithThreshold to apply the policy of the application instead.
+//
+// Returns:
+//   - A SettingsDescription struct describing the cost of the Settings.
+func (s Settings) Describe() SettingsDescription {
+	return s.DescribeWithThreshold(ProfileInteractive)
+}
+
+// DescribeWithThreshold returns a summary of the cost of the Settings like Describe, but computes
+// the Weak field against the given threshold.
+//
+// Parameters:
+//   - threshold: The minimum configuration the Settings have to comply with to not be reported
+//     as weak.
+//
+// Returns:
+//   - A SettingsDescription struct describing the cost of the Settings.
+func (s Settings) DescribeWithThreshold(threshold Settings) SettingsDescription {
+	return SettingsDescription{
+		HashLength:   s.HashLength(),
+		MemoryBytes:  s.EstimatedMemoryBytes(1),
+		MemoryMiB:    s.MemoryMiB(),
+		Weak:         s.weakerThan(threshold),
+		ShortLengths: len(s.lengthWarnings()) > 0,
+		Err:          s.Validate(),
+	}
+}
+
+// InspectResult is a detailed, structured view of a serialized Argon2 hash for forensics and
+// tooling.
+//
+// Fields:
+//   - Settings: The Settings that are read from the serialized settings header.
+//   - HeaderLength: The length of the settings header in bytes. It is SerializedSettingsLength for
+//     the current format, UnversionedSerializedSettingsLength for format version 0 and
+//     LegacySerializedSettingsLength for legacy hashes.
+//   - Length: The actual length of the inspected data in bytes.
+//   - ExpectedLength: The length in bytes that is described by the settings header.
+//   - Salt: The hex encoded salt. If the data is too short, it contains the available part only.
+//   - Key: The hex encoded derived key. If the data is too short, it contains the available part
+//     only.
+//   - CreatedAt: The creation timestamp of the hash, or the zero time if it has none.
+//   - Warnings: Human-readable descriptions of all structural problems that were found. A hash
+//     without warnings is structurally valid and can be validated by this package.
+type InspectResult struct {
+	Settings       Settings
+	HeaderLength   int
+	Length         int
+	ExpectedLength int
+	Salt           string
+	Key            string
+	CreatedAt      time.Time
+	Warnings       []string
+}
+
+// Inspect parses the given serialized Argon2 hash and returns a detailed view of its structure.
+//
+// Unlike Scan and Settings, which reject a structurally invalid hash, Inspect reports as much of
+// the data as possible together with a list of warnings, e.g. for a CLI that examines corrupted
+// or tampered hashes. It applies the same checks as Scan and IsValid: the length of the data
+// has to match the salt and key lengths of the settings header, and the settings have to be
+// within the ranges that are accepted by Settings.Validate, which also rejects unsupported
+// variants. Additionally, a warning is reported if the Argon2 version is not supported by
+// golang.org/x/crypto/argon2. The Argon2 KDF is not executed and the data is not modified.
+//
+// Parameters:
+//   - blob: The serialized Argon2 hash to inspect.
+//
+// Returns:
+//   - An InspectResult describing the hash.
+//   - An error wrapping ErrInvalidHashLength if the data is too short to contain a settings
+//     header.
+func Inspect(blob []byte) (InspectResult, error) {
+	if len(blob) < LegacySerializedSettingsLength {
+		return InspectResult{}, fmt.Errorf("%w, got: %d, expected at least: %d", ErrInvalidHashLength,
+			len(blob), LegacySerializedSettingsLength)
+	}
+
+	layout, ok := parseLayout(blob)
+	if !ok {
+		// The length does not match any of the formats, so we assume the format of the header
+		// that is detected by its format-version byte.
+		layout = hashLayout{settings: settingsFromHeader(blob), headerLength: UnversionedSerializedSettingsLength}
+		if len(blob) >= SerializedSettingsLength && blob[0] == SerializedFormatVersion {
+			layout.headerLength = SerializedSettingsLength
+		}
+	}
+	settings := layout.settings
+	result := InspectResult{
+		Settings:       settings,
+		HeaderLength:   layout.headerLength,
+		Length:         len(blob),
+		ExpectedLength: layout.headerLength + int(settings.payloadLength()),
+	}
+
+	payload := blob[min(layout.headerLength, len(blob)):]
+	salt := payload[:min(uint64(len(payload)), uint64(settings.SaltLength))]
+	key := payload[len(salt):]
+	key = key[:min(uint64(len(key)), uint64(settings.KeyLength))]
+	result.Salt, result.Key = hex.EncodeToString(salt), hex.EncodeToString(key)
+
+	if !ok {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("length mismatch, got: %d, expected: %d",
+			result.Length, result.ExpectedLength))
+	} else if createdAt, found := Argon2(blob).CreatedAt(); found {
+		result.CreatedAt = createdAt
+	}
+	if err := settings.Validate(); err != nil {
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+	if settings.version() != argon2.Version {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("unsupported Argon2 version: %d", settings.version()))
+	}
+	return result, nil
+}
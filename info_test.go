@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArgon2_Info(t *testing.T) {
+	t.Run("info with static values", func(t *testing.T) {
+		info, err := Argon2(testDerived).Info()
+		if err != nil {
+			t.Fatalf("failed to get hash info: %s", err)
+		}
+		want := HashInfo{
+			Variant:    VariantID,
+			Version:    0x13,
+			Memory:     testSettings.Memory,
+			Time:       testSettings.Time,
+			Threads:    testSettings.Threads,
+			SaltLength: testSettings.SaltLength,
+			KeyLength:  testSettings.KeyLength,
+			Weak:       true,
+		}
+		if info != want {
+			t.Errorf("hash info is not as expected, got: %+v, want: %+v", info, want)
+		}
+	})
+	t.Run("info reports weak hashes", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		info, err := derived.Info()
+		if err != nil {
+			t.Fatalf("failed to get hash info: %s", err)
+		}
+		if !info.Weak {
+			t.Error("hash with fast test settings should be reported as weak")
+		}
+	})
+	t.Run("info uses the given threshold", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		info, err := derived.InfoWithThreshold(testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to get hash info: %s", err)
+		}
+		if info.Weak {
+			t.Error("hash that complies with the threshold should not be reported as weak")
+		}
+	})
+	t.Run("info with invalid hash fails", func(t *testing.T) {
+		if _, err := Argon2(nil).Info(); !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("info of invalid hash should fail with invalid hash length, got: %v", err)
+		}
+	})
+}
+
+func TestArgon2_Anonymize(t *testing.T) {
+	t.Run("anonymize with static values", func(t *testing.T) {
+		want := "argon2id[m=262144,t=1,p=4,salt=16B,key=32B]"
+		if got := Argon2(testDerived).Anonymize(); got != want {
+			t.Errorf("anonymized hash is not as expected, got: %s, want: %s", got, want)
+		}
+	})
+	t.Run("anonymize does not contain secret material", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		got := argon.Anonymize()
+		for _, secret := range []string{hex.EncodeToString(argon.Salt()), hex.EncodeToString(argon.Key()),
+			base64.RawStdEncoding.EncodeToString(argon.Salt()), base64.RawStdEncoding.EncodeToString(argon.Key())} {
+			if strings.Contains(got, secret) {
+				t.Errorf("anonymized hash contains secret material: %s", got)
+			}
+		}
+	})
+	t.Run("anonymize with variant", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Variant = VariantI
+		hash := newHash(settings, make([]byte, settings.SaltLength), make([]byte, settings.KeyLength))
+		if got := hash.Anonymize(); !strings.HasPrefix(got, "argon2i[") {
+			t.Errorf("anonymized hash should start with the variant, got: %s", got)
+		}
+	})
+	t.Run("anonymize invalid hash", func(t *testing.T) {
+		for _, hash := range []Argon2{nil, Argon2(testDerived[:len(testDerived)-1])} {
+			if got := hash.Anonymize(); got != "<invalid>" {
+				t.Errorf("anonymized invalid hash is not as expected, got: %s, want: %s", got, "<invalid>")
+			}
+		}
+	})
+}
+
+func TestSettings_Describe(t *testing.T) {
+	t.Run("describe default settings", func(t *testing.T) {
+		want := SettingsDescription{
+			HashLength:  SerializedSettingsLength + 48,
+			MemoryBytes: 1 << 30,
+			MemoryMiB:   1024,
+		}
+		if got := DefaultSettings.Describe(); got != want {
+			t.Errorf("settings description is not as expected, got: %+v, want: %+v", got, want)
+		}
+	})
+	t.Run("describe weak settings", func(t *testing.T) {
+		if !testFastSettings.Describe().Weak {
+			t.Error("fast test settings should be described as weak")
+		}
+		if testFastSettings.DescribeWithThreshold(testFastSettings).Weak {
+			t.Error("settings that comply with the given threshold should not be described as weak")
+		}
+	})
+	t.Run("describe short lengths", func(t *testing.T) {
+		if testFastSettings.Describe().ShortLengths {
+			t.Error("fast test settings should not be described as having short lengths")
+		}
+		description := NewSettings(8, 1, 1, 8, 4).Describe()
+		if !description.ShortLengths || description.Err != nil {
+			t.Errorf("short lengths should be reported without an error, got: %+v", description)
+		}
+	})
+	t.Run("describe invalid settings", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Threads = 0
+		var settingErr *InvalidSettingError
+		if !errors.As(settings.Describe().Err, &settingErr) {
+			t.Errorf("description of invalid settings should contain an InvalidSettingError, got: %v",
+				settings.Describe().Err)
+		}
+	})
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("inspect legacy hash", func(t *testing.T) {
+		result, err := Inspect(testDerived)
+		if err != nil {
+			t.Fatalf("failed to inspect hash: %s", err)
+		}
+		argon := Argon2(testDerived)
+		if !result.Settings.Equal(testSettings) {
+			t.Errorf("settings are not as expected, got: %+v, want: %+v", result.Settings, testSettings)
+		}
+		if result.HeaderLength != LegacySerializedSettingsLength {
+			t.Errorf("header length is not as expected, got: %d, want: %d", result.HeaderLength,
+				LegacySerializedSettingsLength)
+		}
+		if result.Length != len(testDerived) || result.ExpectedLength != len(testDerived) {
+			t.Errorf("lengths are not as expected, got: %d/%d, want: %d", result.Length, result.ExpectedLength,
+				len(testDerived))
+		}
+		if result.Salt != hex.EncodeToString(argon.Salt()) {
+			t.Errorf("salt is not as expected, got: %s, want: %x", result.Salt, argon.Salt())
+		}
+		if result.Key != hex.EncodeToString(argon.Key()) {
+			t.Errorf("key is not as expected, got: %s, want: %x", result.Key, argon.Key())
+		}
+		if len(result.Warnings) != 0 {
+			t.Errorf("valid hash should not have warnings, got: %v", result.Warnings)
+		}
+	})
+	t.Run("inspect current hash", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		result, err := Inspect(derived)
+		if err != nil {
+			t.Fatalf("failed to inspect hash: %s", err)
+		}
+		if result.HeaderLength != SerializedSettingsLength {
+			t.Errorf("header length is not as expected, got: %d, want: %d", result.HeaderLength,
+				SerializedSettingsLength)
+		}
+		if len(result.Warnings) != 0 {
+			t.Errorf("valid hash should not have warnings, got: %v", result.Warnings)
+		}
+		if !result.CreatedAt.IsZero() {
+			t.Errorf("hash without timestamp should have a zero creation time, got: %s", result.CreatedAt)
+		}
+	})
+	t.Run("inspect truncated hash", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		truncated := derived[:len(derived)-4]
+		result, err := Inspect(truncated)
+		if err != nil {
+			t.Fatalf("failed to inspect hash: %s", err)
+		}
+		if result.ExpectedLength != len(derived) || result.Length != len(truncated) {
+			t.Errorf("lengths are not as expected, got: %d/%d, want: %d/%d", result.Length,
+				result.ExpectedLength, len(truncated), len(derived))
+		}
+		if result.Salt != hex.EncodeToString(derived.Salt()) {
+			t.Errorf("salt is not as expected, got: %s, want: %x", result.Salt, derived.Salt())
+		}
+		if want := hex.EncodeToString(derived.Key()[:testFastSettings.KeyLength-4]); result.Key != want {
+			t.Errorf("partial key is not as expected, got: %s, want: %s", result.Key, want)
+		}
+		if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "length mismatch") {
+			t.Errorf("truncated hash should have a length mismatch warning, got: %v", result.Warnings)
+		}
+	})
+	t.Run("inspect hash with out-of-range settings", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Threads = 0
+		settings.Version = 0x10
+		hash := newHash(settings, make([]byte, settings.SaltLength), make([]byte, settings.KeyLength))
+		result, err := Inspect(hash)
+		if err != nil {
+			t.Fatalf("failed to inspect hash: %s", err)
+		}
+		if len(result.Warnings) != 2 {
+			t.Errorf("hash should have a settings and a version warning, got: %v", result.Warnings)
+		}
+	})
+	t.Run("inspect timestamped hash", func(t *testing.T) {
+		derived, err := DeriveWithOptions(testPassPhrase, WithMemory(testFastSettings.Memory),
+			WithTime(testFastSettings.Time), WithThreads(testFastSettings.Threads), WithTimestamp(true))
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		result, err := Inspect(derived)
+		if err != nil {
+			t.Fatalf("failed to inspect hash: %s", err)
+		}
+		createdAt, _ := derived.CreatedAt()
+		if !result.CreatedAt.Equal(createdAt) || result.CreatedAt.IsZero() {
+			t.Errorf("creation time is not as expected, got: %s, want: %s", result.CreatedAt, createdAt)
+		}
+	})
+	t.Run("inspect too short data fails", func(t *testing.T) {
+		if _, err := Inspect(testDerived[:10]); !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("inspect of too short data should fail with ErrInvalidHashLength, got: %v", err)
+		}
+	})
+}
+
+func FuzzInspect(f *testing.F) {
+	f.Add(testDerived)
+	f.Add(testDerived[:len(testDerived)-2])
+	f.Add(testDerived[:LegacySerializedSettingsLength])
+	f.Add(bytes.Repeat([]byte{0xff}, SerializedSettingsLength+8))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		result, err := Inspect(data)
+		if err != nil {
+			return
+		}
+		if len(result.Warnings) == 0 && !Argon2(data).IsValid() {
+			t.Errorf("hash without warnings should be valid: %x", data)
+		}
+	})
+}
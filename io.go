@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WriteTo implements the io.WriterTo interface and writes the Argon2 hash to the given writer.
+//
+// The hash is written in the current serialized format, i.e. the settings header including the
+// version byte, followed by the salt and the derived key. Legacy hashes are converted to the
+// current format while writing. Since the header describes the length of the salt and key, the
+// written hash is self-describing and can be read back with ReadFrom, which allows to stream
+// many hashes one after another.
+//
+// Parameters:
+//   - w: The io.Writer the hash is written to.
+//
+// Returns:
+//   - The number of bytes written.
+//   - An error wrapping ErrInvalidHashLength if the hash is structurally invalid, or any error
+//     returned by the writer.
+func (a Argon2) WriteTo(w io.Writer) (int64, error) {
+	layout, ok := parseLayout(a)
+	if !ok {
+		return 0, fmt.Errorf("failed to write Argon2 hash: %w", ErrInvalidHashLength)
+	}
+	data := []byte(a)
+	if layout.headerLength != SerializedSettingsLength {
+		data = newHash(layout.settings, layout.salt(a), layout.key(a))
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom implements the io.ReaderFrom interface and reads a single Argon2 hash from the given
+// reader.
+//
+// It first reads the settings header of SerializedSettingsLength bytes and deserializes it to
+// learn the length of the salt and key. Since WriteTo always writes the current format, the header
+// must start with SerializedFormatVersion. It then reads exactly that many bytes, so that the
+// reader is positioned at the start of the next hash. This is the counterpart to WriteTo.
+// Unlike the usual io.ReaderFrom semantics, ReadFrom does not read until EOF, but only reads a
+// single hash, so it can be called in a loop until it returns io.EOF.
+//
+// Parameters:
+//   - r: The io.Reader the hash is read from.
+//
+// Returns:
+//   - The number of bytes read.
+//   - io.EOF if the reader has no more data, io.ErrUnexpectedEOF if the reader ends in the middle
+//     of a hash, an error wrapping ErrInvalidHash if the header has an unknown format version or
+//     describes a salt or key that is too long, a Memory or Time above MaxMemory or MaxTime or an
+//     unsupported Variant, in which case the error also wraps ErrUnsupportedVariant, or any other
+//     error returned by the reader.
+func (a *Argon2) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, SerializedSettingsLength)
+	n, err := io.ReadFull(r, header)
+	if err != nil {
+		return int64(n), err
+	}
+	if header[0] != SerializedFormatVersion {
+		return int64(n), fmt.Errorf("%w: unsupported format version: %d", ErrInvalidHash, header[0])
+	}
+	settings := settingsFromHeader(header)
+	if err = settings.validateHeader(); err != nil {
+		return int64(n), fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+
+	data := make([]byte, settings.HashLength())
+	copy(data, header)
+	m, err := io.ReadFull(r, data[SerializedSettingsLength:])
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return int64(n + m), err
+	}
+	*a = data
+	return int64(n + m), nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. It returns a copy of the raw
+// Argon2 hash, so that codecs like encoding/gob can encode it.
+func (a Argon2) MarshalBinary() ([]byte, error) {
+	return bytes.Clone(a), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. Like Scan, it checks that
+// the length of the data matches the salt and key lengths of its serialized settings, that these
+// lengths do not exceed MaxSaltLength and MaxKeyLength and that the Memory and Time do not exceed
+// MaxMemory and MaxTime, before a copy of the data is assigned. Hashes with an unsupported Variant
+// are rejected with an error wrapping ErrUnsupportedVariant. Empty data results in a nil Argon2.
+func (a *Argon2) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		*a = nil
+		return nil
+	}
+	layout, ok := parseLayout(data)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal Argon2 hash: %w", ErrInvalidHashLength)
+	}
+	if err := layout.settings.validateHeader(); err != nil {
+		return fmt.Errorf("failed to unmarshal Argon2 hash: %w: %w", ErrInvalidHash, err)
+	}
+	*a = bytes.Clone(data)
+	return nil
+}
+
+// hashLengthPrefixSize is the size in bytes of the length prefix of every hash that is stored in
+// a container created by EncodeHashes.
+const hashLengthPrefixSize = 4
+
+// EncodeHashes packs multiple Argon2 hashes into a single byte slice.
+//
+// This allows to store a set of acceptable hashes in a single column, e.g. the old and the new
+// hash during a credential migration. Every hash is prefixed with its length as 4 byte unsigned
+// integer in little-endian byte order, followed by the raw hash bytes. The hashes are stored as
+// they are, so invalid hashes are detected by DecodeHashes.
+//
+// Parameters:
+//   - hashes: The Argon2 hashes to pack.
+//
+// Returns:
+//   - A byte slice containing the length-prefixed hashes.
+func EncodeHashes(hashes []Argon2) []byte {
+	size := 0
+	for _, hash := range hashes {
+		size += hashLengthPrefixSize + len(hash)
+	}
+	buffer := make([]byte, 0, size)
+	for _, hash := range hashes {
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(len(hash)))
+		buffer = append(buffer, hash...)
+	}
+	return buffer
+}
+
+// DecodeHashes unpacks the Argon2 hashes from a byte slice that was created by EncodeHashes.
+//
+// Every hash is checked to be structurally valid, i.e. its length has to match the salt and key
+// lengths of its serialized settings. Like UnmarshalBinary, the salt and key lengths must not
+// exceed MaxSaltLength and MaxKeyLength, the Memory and Time must not exceed MaxMemory and MaxTime
+// and the Variant must be supported. The returned hashes are copies and do not share memory with
+// the given byte slice.
+//
+// Parameters:
+//   - data: The byte slice containing the length-prefixed hashes.
+//
+// Returns:
+//   - The unpacked Argon2 hashes in the order they were packed.
+//   - An error if the data is truncated, an error wrapping ErrInvalidHashLength if any of the
+//     hashes is structurally invalid, or an error wrapping ErrInvalidHash if the settings of any
+//     of the hashes are rejected.
+func DecodeHashes(data []byte) ([]Argon2, error) {
+	var hashes []Argon2
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < hashLengthPrefixSize {
+			return nil, fmt.Errorf("failed to decode hashes: truncated length prefix at offset %d", offset)
+		}
+		length := uint64(binary.LittleEndian.Uint32(data[offset:]))
+		offset += hashLengthPrefixSize
+		if uint64(len(data)-offset) < length {
+			return nil, fmt.Errorf("failed to decode hashes: truncated hash at offset %d", offset)
+		}
+		hash := Argon2(bytes.Clone(data[offset : offset+int(length)]))
+		layout, ok := parseLayout(hash)
+		if !ok {
+			return nil, fmt.Errorf("failed to decode hashes: hash %d: %w", len(hashes), ErrInvalidHashLength)
+		}
+		if err := layout.settings.validateHeader(); err != nil {
+			return nil, fmt.Errorf("failed to decode hashes: hash %d: %w: %w", len(hashes), ErrInvalidHash, err)
+		}
+		hashes = append(hashes, hash)
+		offset += int(length)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestArgon2_WriteTo(t *testing.T) {
+	t.Run("write derived hash", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		buffer := bytes.NewBuffer(nil)
+		n, err := derived.WriteTo(buffer)
+		if err != nil {
+			t.Fatalf("failed to write hash: %s", err)
+		}
+		if n != int64(len(derived)) {
+			t.Errorf("unexpected number of bytes written, got: %d, want: %d", n, len(derived))
+		}
+		if !bytes.Equal(buffer.Bytes(), derived) {
+			t.Errorf("written hash is not as expected, got: %x, want: %x", buffer.Bytes(), []byte(derived))
+		}
+	})
+	t.Run("write legacy hash in current format", func(t *testing.T) {
+		buffer := bytes.NewBuffer(nil)
+		n, err := Argon2(testDerived).WriteTo(buffer)
+		if err != nil {
+			t.Fatalf("failed to write hash: %s", err)
+		}
+		if n != int64(len(testDerived)+2) {
+			t.Errorf("unexpected number of bytes written, got: %d, want: %d", n, len(testDerived)+2)
+		}
+		written := Argon2(buffer.Bytes())
+		if !bytes.Equal(written.Salt(), Argon2(testDerived).Salt()) ||
+			!bytes.Equal(written.Key(), Argon2(testDerived).Key()) {
+			t.Error("written hash does not contain the salt and key of the legacy hash")
+		}
+	})
+	t.Run("write invalid hash fails", func(t *testing.T) {
+		_, err := Argon2(testDerived[:len(testDerived)-2]).WriteTo(io.Discard)
+		if !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("writing invalid hash should fail with invalid hash length, got: %v", err)
+		}
+	})
+	t.Run("write to failing writer", func(t *testing.T) {
+		if _, err := Argon2(testDerived).WriteTo(failWriter{}); err == nil {
+			t.Error("writing to failing writer should fail")
+		}
+	})
+}
+
+func TestArgon2_ReadFrom(t *testing.T) {
+	t.Run("read multiple hashes from stream", func(t *testing.T) {
+		buffer := bytes.NewBuffer(nil)
+		var hashes []Argon2
+		for _, password := range []string{"one", "two", "three"} {
+			derived, err := Derive(password, testFastSettings)
+			if err != nil {
+				t.Fatalf("failed to derive hash: %s", err)
+			}
+			if _, err = derived.WriteTo(buffer); err != nil {
+				t.Fatalf("failed to write hash: %s", err)
+			}
+			hashes = append(hashes, derived)
+		}
+		for i, want := range hashes {
+			var argon Argon2
+			n, err := (&argon).ReadFrom(buffer)
+			if err != nil {
+				t.Fatalf("failed to read hash %d: %s", i, err)
+			}
+			if n != int64(len(want)) {
+				t.Errorf("unexpected number of bytes read, got: %d, want: %d", n, len(want))
+			}
+			if !bytes.Equal(argon, want) {
+				t.Errorf("read hash %d is not as expected, got: %x, want: %x", i, []byte(argon), []byte(want))
+			}
+		}
+		var argon Argon2
+		if _, err := (&argon).ReadFrom(buffer); !errors.Is(err, io.EOF) {
+			t.Errorf("reading from exhausted stream should return EOF, got: %v", err)
+		}
+	})
+	t.Run("read truncated hash fails", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		for _, length := range []int{1, SerializedSettingsLength, len(derived) - 1} {
+			var argon Argon2
+			_, err = (&argon).ReadFrom(bytes.NewReader(derived[:length]))
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("reading %d bytes should fail with unexpected EOF, got: %v", length, err)
+			}
+		}
+	})
+	t.Run("read hash with unknown format version fails", func(t *testing.T) {
+		var argon Argon2
+		_, err := (&argon).ReadFrom(bytes.NewReader(testDerived))
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("reading hash with unknown format version should fail with invalid hash, got: %v", err)
+		}
+	})
+	t.Run("read hash with excessive memory fails", func(t *testing.T) {
+		header := testFastSettings
+		header.Memory = MaxMemory + 1
+		data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+		var argon Argon2
+		if _, err := (&argon).ReadFrom(bytes.NewReader(data)); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("reading hash with excessive memory should fail with invalid hash, got: %v", err)
+		}
+	})
+	t.Run("read hash with unsupported variant fails", func(t *testing.T) {
+		for _, variant := range []Variant{VariantD, 63} {
+			header := testFastSettings
+			header.Variant = variant
+			data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+			var argon Argon2
+			_, err := (&argon).ReadFrom(bytes.NewReader(data))
+			if !errors.Is(err, ErrUnsupportedVariant) || !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("reading hash with %s should fail with unsupported variant, got: %v", variant, err)
+			}
+		}
+	})
+	t.Run("read hash with too long salt fails", func(t *testing.T) {
+		header := testFastSettings
+		header.SaltLength = 4294967295
+		var argon Argon2
+		_, err := (&argon).ReadFrom(bytes.NewReader(header.Serialize()))
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("reading hash with too long salt should fail with invalid hash, got: %v", err)
+		}
+	})
+}
+
+func TestArgon2_MarshalBinary(t *testing.T) {
+	t.Run("marshal returns a copy", func(t *testing.T) {
+		argon := Argon2(bytes.Clone(testDerived))
+		data, err := argon.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to marshal hash: %s", err)
+		}
+		if !bytes.Equal(data, testDerived) {
+			t.Errorf("marshaled hash is not as expected, got: %x, want: %x", data, testDerived)
+		}
+		argon.Zero()
+		if !bytes.Equal(data, testDerived) {
+			t.Error("marshaled hash should not be affected by wiping the hash")
+		}
+	})
+	t.Run("gob round-trip in struct", func(t *testing.T) {
+		type session struct {
+			User string
+			Hash Argon2
+		}
+		buffer := bytes.NewBuffer(nil)
+		if err := gob.NewEncoder(buffer).Encode(session{User: "toni", Hash: testDerived}); err != nil {
+			t.Fatalf("failed to gob encode session: %s", err)
+		}
+		var decoded session
+		if err := gob.NewDecoder(buffer).Decode(&decoded); err != nil {
+			t.Fatalf("failed to gob decode session: %s", err)
+		}
+		if !bytes.Equal(decoded.Hash, testDerived) {
+			t.Errorf("decoded hash is not as expected, got: %x, want: %x", []byte(decoded.Hash), testDerived)
+		}
+	})
+}
+
+func TestArgon2_UnmarshalBinary(t *testing.T) {
+	t.Run("unmarshal valid hash", func(t *testing.T) {
+		data := bytes.Clone(testDerived)
+		var argon Argon2
+		if err := (&argon).UnmarshalBinary(data); err != nil {
+			t.Fatalf("failed to unmarshal hash: %s", err)
+		}
+		data[len(data)-1] ^= 0xff
+		if !bytes.Equal(argon, testDerived) {
+			t.Error("unmarshaled hash should be a copy of the data")
+		}
+	})
+	t.Run("unmarshal empty data", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		if err := (&argon).UnmarshalBinary(nil); err != nil {
+			t.Fatalf("failed to unmarshal empty data: %s", err)
+		}
+		if argon != nil {
+			t.Error("hash should be nil after unmarshaling empty data")
+		}
+	})
+	t.Run("unmarshal invalid hash fails", func(t *testing.T) {
+		var argon Argon2
+		err := (&argon).UnmarshalBinary(testDerived[:len(testDerived)-2])
+		if !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("unmarshaling invalid hash should fail with invalid hash length, got: %v", err)
+		}
+	})
+	t.Run("unmarshal hash with too long key fails", func(t *testing.T) {
+		header := testFastSettings
+		header.KeyLength = MaxKeyLength + 1
+		data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+		var argon Argon2
+		if err := (&argon).UnmarshalBinary(data); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("unmarshaling hash with too long key should fail with invalid hash, got: %v", err)
+		}
+	})
+	t.Run("unmarshal hash with unsupported variant fails", func(t *testing.T) {
+		for _, variant := range []Variant{VariantD, 63} {
+			header := testFastSettings
+			header.Variant = variant
+			data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+			var argon Argon2
+			err := (&argon).UnmarshalBinary(data)
+			if !errors.Is(err, ErrUnsupportedVariant) || !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("unmarshaling hash with %s should fail with unsupported variant, got: %v", variant, err)
+			}
+			if argon != nil {
+				t.Errorf("unmarshaling hash with %s should not have assigned the hash", variant)
+			}
+		}
+	})
+	t.Run("unmarshal hash with excessive time fails", func(t *testing.T) {
+		header := testFastSettings
+		header.Time = MaxTime + 1
+		data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+		var argon Argon2
+		if err := (&argon).UnmarshalBinary(data); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("unmarshaling hash with excessive time should fail with invalid hash, got: %v", err)
+		}
+	})
+}
+
+func TestEncodeHashes(t *testing.T) {
+	t.Run("encode and decode round-trip", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		hashes := []Argon2{testDerived, derived}
+		encoded := EncodeHashes(hashes)
+		if len(encoded) != 2*hashLengthPrefixSize+len(testDerived)+len(derived) {
+			t.Errorf("encoded length is not as expected, got: %d", len(encoded))
+		}
+		decoded, err := DecodeHashes(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode hashes: %s", err)
+		}
+		if len(decoded) != len(hashes) {
+			t.Fatalf("unexpected number of decoded hashes, got: %d, want: %d", len(decoded), len(hashes))
+		}
+		for i := range hashes {
+			if !bytes.Equal(decoded[i], hashes[i]) {
+				t.Errorf("decoded hash %d is not as expected, got: %x, want: %x", i, []byte(decoded[i]),
+					[]byte(hashes[i]))
+			}
+		}
+	})
+	t.Run("encode and decode empty set", func(t *testing.T) {
+		decoded, err := DecodeHashes(EncodeHashes(nil))
+		if err != nil {
+			t.Fatalf("failed to decode hashes: %s", err)
+		}
+		if len(decoded) != 0 {
+			t.Errorf("expected no hashes, got: %d", len(decoded))
+		}
+	})
+}
+
+func TestDecodeHashes(t *testing.T) {
+	encoded := EncodeHashes([]Argon2{testDerived})
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated length prefix", encoded[:2]},
+		{"truncated hash", encoded[:len(encoded)-1]},
+		{"trailing bytes", append(bytes.Clone(encoded), 0x01)},
+		{"invalid hash", EncodeHashes([]Argon2{testDerived[:len(testDerived)-2]})},
+		{"huge length prefix", []byte{0xff, 0xff, 0xff, 0xff, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeHashes(tt.data); err == nil {
+				t.Errorf("decoding %s should fail", tt.name)
+			}
+		})
+	}
+	t.Run("well-formed hash with invalid settings", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		tests := []struct {
+			name   string
+			offset int
+			value  byte
+		}{
+			{"memory above maximum", 4, 0xff},
+			{"time above maximum", 8, 0xff},
+			{"unknown variant", 10, 0x0f},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				hash := bytes.Clone(derived)
+				hash[tt.offset] = tt.value
+				if _, err := DecodeHashes(EncodeHashes([]Argon2{derived, hash})); !errors.Is(err, ErrInvalidHash) {
+					t.Errorf("decoding hash with %s should fail with invalid hash, got: %v", tt.name, err)
+				}
+			})
+		}
+	})
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (n int, err error) {
+	return 0, errors.New("intentionally failed to write")
+}
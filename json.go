@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonHash is the structured JSON representation of an Argon2 hash.
+//
+// The field names are part of the public API and are kept stable:
+//   - alg: The name of the Argon2 variant, e.g. "argon2id".
+//   - version: The Argon2 version, e.g. 19.
+//   - memory: The memory cost in KiB.
+//   - time: The number of iterations.
+//   - threads: The number of parallel threads.
+//   - salt: The salt, encoded using standard base64 encoding with padding.
+//   - hash: The derived key, encoded using standard base64 encoding with padding.
+//   - keyed: Whether the hash was derived with DeriveWithKey. Omitted for unkeyed hashes.
+//   - created: The creation timestamp of hashes derived with WithTimestamp in seconds since the
+//     Unix epoch. Omitted for hashes without a creation timestamp.
+type jsonHash struct {
+	Alg     string `json:"alg"`
+	Version uint8  `json:"version"`
+	Memory  uint32 `json:"memory"`
+	Time    uint32 `json:"time"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Hash    []byte `json:"hash"`
+	Keyed   bool   `json:"keyed,omitempty"`
+	Created *int64 `json:"created,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+//
+// The Argon2 hash is encoded as a JSON object with individual fields for the parameters, the
+// salt and the derived key, e.g.:
+//
+//	{"alg":"argon2id","version":19,"memory":131072,"time":3,"threads":4,"salt":"<b64>","hash":"<b64>"}
+//
+// Keyed hashes carry an additional "keyed":true field and hashes with a creation timestamp an
+// additional "created" field, so that both survive a round trip through UnmarshalJSON.
+//
+// An empty Argon2 hash is encoded as JSON null. Since MarshalJSON takes precedence over
+// MarshalText, the encoding/json package always uses this structured form.
+//
+// Returns:
+//   - The JSON encoded Argon2 hash.
+//   - An error if the Argon2 hash is structurally invalid or uses an unknown variant.
+func (a Argon2) MarshalJSON() ([]byte, error) {
+	if len(a) == 0 {
+		return []byte("null"), nil
+	}
+	layout, ok := parseLayout(a)
+	if !ok {
+		return nil, fmt.Errorf("failed to encode Argon2 hash: %w", ErrInvalidHashLength)
+	}
+	settings := layout.settings
+	if settings.Variant > VariantD {
+		return nil, fmt.Errorf("failed to encode Argon2 hash: unknown Argon2 variant: %d", settings.Variant)
+	}
+	encoded := jsonHash{
+		Alg:     settings.Variant.String(),
+		Version: settings.version(),
+		Memory:  settings.Memory,
+		Time:    settings.Time,
+		Threads: settings.Threads,
+		Salt:    layout.salt(a),
+		Hash:    layout.key(a),
+		Keyed:   settings.Keyed,
+	}
+	if settings.Timestamped {
+		created := int64(binary.LittleEndian.Uint64(layout.timestamp(a)))
+		encoded.Created = &created
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+//
+// It reconstructs the native byte layout from the structured JSON object that is produced by
+// MarshalJSON. For compatibility with the text representation, a JSON string containing a
+// PHC string is accepted as well. JSON null results in an empty Argon2 hash.
+//
+// Parameters:
+//   - data: The JSON encoded Argon2 hash.
+//
+// Returns:
+//   - An error if the JSON data is invalid or the resulting settings are outside the allowed ranges.
+func (a *Argon2) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*a = nil
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return fmt.Errorf("failed to decode Argon2 hash: %w", err)
+		}
+		return a.UnmarshalText([]byte(text))
+	}
+
+	var decoded jsonHash
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("failed to decode Argon2 hash: %w", err)
+	}
+	variant, ok := parseVariant(decoded.Alg)
+	if !ok {
+		return fmt.Errorf("failed to decode Argon2 hash: unknown algorithm: %q", decoded.Alg)
+	}
+	settings := Settings{
+		Memory:      decoded.Memory,
+		Time:        decoded.Time,
+		Threads:     decoded.Threads,
+		SaltLength:  uint32(len(decoded.Salt)),
+		KeyLength:   uint32(len(decoded.Hash)),
+		Variant:     variant,
+		Version:     decoded.Version,
+		Keyed:       decoded.Keyed,
+		Timestamped: decoded.Created != nil,
+	}
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("failed to decode Argon2 hash: %w", err)
+	}
+
+	hash := newHash(settings, decoded.Salt, decoded.Hash)
+	if decoded.Created != nil {
+		binary.LittleEndian.PutUint64(hash[len(hash)-TimestampLength:], uint64(*decoded.Created))
+	}
+	*a = hash
+	return nil
+}
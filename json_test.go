@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+const testJSON = `{"alg":"argon2id","version":19,"memory":262144,"time":1,"threads":4,` +
+	`"salt":"8uCfrPniGiJolKHZe9bP+w==","hash":"rr12IYDBfIe9vVep77LJt5+BDa9Pq1W2enBf7VIh37M="}`
+
+func TestArgon2_MarshalJSON(t *testing.T) {
+	t.Run("marshal static values", func(t *testing.T) {
+		data, err := json.Marshal(Argon2(testDerived))
+		if err != nil {
+			t.Fatalf("failed to marshal JSON: %s", err)
+		}
+		if string(data) != testJSON {
+			t.Errorf("JSON is not as expected, got: %s, want: %s", data, testJSON)
+		}
+	})
+	t.Run("marshal empty hash", func(t *testing.T) {
+		data, err := json.Marshal(Argon2(nil))
+		if err != nil {
+			t.Fatalf("failed to marshal JSON: %s", err)
+		}
+		if string(data) != "null" {
+			t.Errorf("JSON is not as expected, got: %s, want: null", data)
+		}
+	})
+	t.Run("marshal invalid hash fails", func(t *testing.T) {
+		if _, err := json.Marshal(Argon2(testDerived[:10])); err == nil {
+			t.Fatal("marshal of invalid hash should have failed")
+		}
+	})
+	t.Run("marshal hash with unknown variant fails", func(t *testing.T) {
+		argon := bytes.Clone(testDerived)
+		argon[9] = 99
+		if _, err := json.Marshal(Argon2(argon)); err == nil {
+			t.Fatal("marshal of hash with unknown variant should have failed")
+		}
+	})
+}
+
+func TestArgon2_UnmarshalJSON(t *testing.T) {
+	t.Run("unmarshal round-trips with derive", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from password string: %s", err)
+		}
+		data, err := json.Marshal(derived)
+		if err != nil {
+			t.Fatalf("failed to marshal JSON: %s", err)
+		}
+		var argon Argon2
+		if err = json.Unmarshal(data, &argon); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if !bytes.Equal(argon, derived) {
+			t.Errorf("unmarshalled hash is not as expected, got: %x, want: %x", argon, derived)
+		}
+	})
+	t.Run("unmarshal round-trips keyed hash", func(t *testing.T) {
+		key := []byte("server-side-key")
+		derived, err := DeriveWithKey(testPassPhrase, key, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive keyed hash: %s", err)
+		}
+		data, err := json.Marshal(derived)
+		if err != nil {
+			t.Fatalf("failed to marshal JSON: %s", err)
+		}
+		var argon Argon2
+		if err = json.Unmarshal(data, &argon); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if !bytes.Equal(argon, derived) {
+			t.Errorf("unmarshalled keyed hash is not as expected, got: %x, want: %x", argon, derived)
+		}
+		if !argon.ValidateWithKey(testPassPhrase, key) {
+			t.Error("unmarshalled keyed hash should be valid with its key")
+		}
+	})
+	t.Run("unmarshal round-trips timestamped hash", func(t *testing.T) {
+		derived, err := DeriveWithOptions(testPassPhrase, WithMemory(testFastSettings.Memory),
+			WithTime(testFastSettings.Time), WithThreads(testFastSettings.Threads), WithTimestamp(true))
+		if err != nil {
+			t.Fatalf("failed to derive timestamped hash: %s", err)
+		}
+		data, err := json.Marshal(derived)
+		if err != nil {
+			t.Fatalf("failed to marshal JSON: %s", err)
+		}
+		var argon Argon2
+		if err = json.Unmarshal(data, &argon); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if !bytes.Equal(argon, derived) {
+			t.Errorf("unmarshalled timestamped hash is not as expected, got: %x, want: %x", argon, derived)
+		}
+		if _, ok := argon.CreatedAt(); !ok {
+			t.Error("unmarshalled timestamped hash should carry a creation timestamp")
+		}
+	})
+	t.Run("unmarshal static values", func(t *testing.T) {
+		var argon Argon2
+		if err := json.Unmarshal([]byte(testJSON), &argon); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if !argon.Validate(testPassPhrase) {
+			t.Error("unmarshalled hash is not valid but should be")
+		}
+	})
+	t.Run("unmarshal PHC string", func(t *testing.T) {
+		var argon Argon2
+		if err := json.Unmarshal([]byte(`"`+testPHC+`"`), &argon); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if !argon.Validate(testPassPhrase) {
+			t.Error("unmarshalled hash is not valid but should be")
+		}
+	})
+	t.Run("unmarshal null", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		if err := json.Unmarshal([]byte("null"), &argon); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if argon != nil {
+			t.Error("hash is not nil after unmarshalling null")
+		}
+	})
+	t.Run("unmarshal invalid JSON fails", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input string
+		}{
+			{"invalid object", `{"alg":}`},
+			{"invalid string", `"invalid"`},
+			{"unknown algorithm", `{"alg":"scrypt","memory":64,"time":1,"threads":1,"salt":"c29tZXNhbHQ=","hash":"aGFzaA=="}`},
+			{"invalid settings", `{"alg":"argon2id","memory":64,"time":0,"threads":1,"salt":"c29tZXNhbHQ=","hash":"aGFzaA=="}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var argon Argon2
+				if err := argon.UnmarshalJSON([]byte(tt.input)); err == nil {
+					t.Errorf("unmarshal of %s should have failed", tt.input)
+				}
+			})
+		}
+	})
+}
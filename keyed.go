@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// DeriveWithKey generates a keyed Argon2 hash using the provided password, secret key and
+// settings.
+//
+// The Argon2 specification supports a keyed mode with a secret key K that is separate from the
+// salt and the associated data, e.g. for deployments where the key is held in an HSM. Since
+// golang.org/x/crypto/argon2 does not expose the secret key input, the key is mixed into the
+// derivation by computing HMAC-SHA256 over the password, keyed with the secret key, in the same
+// way as in DeriveWithSecret. The resulting 32 byte MAC is then used as the password input for the
+// Argon2 KDF. The resulting hashes are therefore not compatible with other implementations of the
+// Argon2 keyed mode.
+//
+// Unlike DeriveWithSecret, the hash is marked as keyed by setting the highest bit of the variant
+// byte in the serialized settings, which is reflected by the Keyed field of the Settings. The key
+// itself is never stored. Keyed hashes can only be validated with ValidateWithKey, and unkeyed
+// hashes never validate with ValidateWithKey, so the two kinds of hashes cannot collide.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - key: The secret key. It must not be empty.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the key is empty, ErrPasswordTooLong if the password exceeds MaxPasswordLength,
+//     or an error if any issues occur during the hash generation.
+func DeriveWithKey(password string, key []byte, settings Settings) (Argon2, error) {
+	if len(key) == 0 {
+		return nil, errors.New("key must not be empty")
+	}
+	if err := checkPasswordLength([]byte(password), MaxPasswordLength); err != nil {
+		return nil, err
+	}
+	return derive(pepper([]byte(password), key), nil, options{settings: settings, keyed: true}, rand.Reader)
+}
+
+// ValidateWithKey verifies whether the given password matches a keyed Argon2 hash that was
+// created with DeriveWithKey using the given secret key.
+//
+// The password is mixed with the key in the same way as in DeriveWithKey before it is
+// validated. All timing attack mitigations of Validate apply. The validation fails if the
+// hash is not keyed, if the key is empty or wrong, or if the password exceeds MaxPasswordLength.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//   - key: The secret key that was used to create the hash.
+//
+// Returns:
+//   - true if the password is valid and matches the stored keyed Argon2 hash.
+func (a Argon2) ValidateWithKey(password string, key []byte) bool {
+	peppered, tooLong := truncatePassword([]byte(password), MaxPasswordLength)
+	_, err := a.validateWithDummy(pepper(peppered, key), nil, true, nil, MaxPasswordLength)
+	return err == nil && len(key) > 0 && !tooLong
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testKey = []byte("hsm-b4ck3d-s3cr3t-k3y")
+
+func TestDeriveWithKey(t *testing.T) {
+	t.Run("derive with key succeeds", func(t *testing.T) {
+		derived, err := DeriveWithKey(testPassPhrase, testKey, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive keyed hash: %s", err)
+		}
+		settings, err := derived.Settings()
+		if err != nil {
+			t.Fatalf("failed to read settings of keyed hash: %s", err)
+		}
+		if !settings.Keyed {
+			t.Error("derived hash should be marked as keyed")
+		}
+		if settings.Variant != testFastSettings.Variant {
+			t.Errorf("keyed hash variant is not as expected, got: %d, want: %d", settings.Variant,
+				testFastSettings.Variant)
+		}
+		if !derived.ValidateWithKey(testPassPhrase, testKey) {
+			t.Error("derived hash is not valid with the same key")
+		}
+	})
+	t.Run("derive with too long password fails", func(t *testing.T) {
+		tooLong := strings.Repeat("a", MaxPasswordLength+1)
+		if _, err := DeriveWithKey(tooLong, testKey, testFastSettings); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("derive with too long password should have failed with ErrPasswordTooLong, got: %v", err)
+		}
+	})
+	t.Run("validate with too long password fails", func(t *testing.T) {
+		maxPassword := strings.Repeat("a", MaxPasswordLength)
+		derived, err := DeriveWithKey(maxPassword, testKey, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateWithKey(maxPassword, testKey) {
+			t.Error("derived hash is not valid with the maximum password length")
+		}
+		if derived.ValidateWithKey(maxPassword+"a", testKey) {
+			t.Error("hash should not be valid with a too long password")
+		}
+	})
+	t.Run("derive with empty key fails", func(t *testing.T) {
+		if _, err := DeriveWithKey(testPassPhrase, nil, testFastSettings); err == nil {
+			t.Fatal("derive with empty key should have failed")
+		}
+	})
+	t.Run("derive ignores keyed flag in settings", func(t *testing.T) {
+		settings := testFastSettings
+		settings.Keyed = true
+		derived, err := Derive(testPassPhrase, settings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if stored, _ := derived.Settings(); stored.Keyed {
+			t.Error("hash derived without a key should not be marked as keyed")
+		}
+	})
+}
+
+func TestArgon2_ValidateWithKey(t *testing.T) {
+	keyed, err := DeriveWithKey(testPassPhrase, testKey, testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive keyed hash: %s", err)
+	}
+	t.Run("validate with wrong key fails", func(t *testing.T) {
+		if keyed.ValidateWithKey(testPassPhrase, []byte("wrong-key")) {
+			t.Error("validation with wrong key should have failed")
+		}
+	})
+	t.Run("validate with empty key fails", func(t *testing.T) {
+		if keyed.ValidateWithKey(testPassPhrase, nil) {
+			t.Error("validation with empty key should have failed")
+		}
+	})
+	t.Run("validate with wrong password fails", func(t *testing.T) {
+		if keyed.ValidateWithKey("wrong password", testKey) {
+			t.Error("validation with wrong password should have failed")
+		}
+	})
+	t.Run("validate keyed hash without key fails", func(t *testing.T) {
+		if keyed.Validate(testPassPhrase) {
+			t.Error("validation of keyed hash without key should have failed")
+		}
+		if keyed.Validate(string(pepper([]byte(testPassPhrase), testKey))) {
+			t.Error("validation of keyed hash with the mixed password should have failed")
+		}
+	})
+	t.Run("validate unkeyed hash with key fails", func(t *testing.T) {
+		peppered, err := DeriveWithSecret(testPassPhrase, testKey, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with secret: %s", err)
+		}
+		if peppered.ValidateWithKey(testPassPhrase, testKey) {
+			t.Error("validation of unkeyed hash with key should have failed")
+		}
+	})
+	t.Run("keyed hash cannot be encoded as PHC string", func(t *testing.T) {
+		if _, err := keyed.MarshalPHC(); err == nil {
+			t.Error("encoding keyed hash as PHC string should have failed")
+		}
+	})
+}
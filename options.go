@@ -0,0 +1,341 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"crypto/rand"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Option represents a functional option that is used to configure the Argon2 hash generation.
+//
+// Options are applied in the given order, so if the same option is provided multiple times,
+// the last one wins.
+type Option func(*options)
+
+// options holds the configuration that is assembled from a list of functional options.
+type options struct {
+	settings      Settings
+	allowZeroSalt bool
+	keyed         bool
+	rejectEmpty   bool
+	normalize     bool
+	unicodeForm   norm.Form
+	skipEmpty     bool
+	timestamp     bool
+	createdAt     time.Time
+
+	// maxPasswordLength is the maximum password length. Zero means MaxPasswordLength and a
+	// negative value disables the check.
+	maxPasswordLength int
+}
+
+// WithMemory sets the memory cost for the Argon2 hash generation in kilobytes.
+//
+// Parameters:
+//   - memory: The amount of memory (in KB) to be used by the Argon2 algorithm.
+//
+// Returns:
+//   - An Option that overrides the Memory field of the settings.
+func WithMemory(memory uint32) Option {
+	return func(o *options) {
+		o.settings.Memory = memory
+	}
+}
+
+// WithMemoryMiB sets the memory cost for the Argon2 hash generation in mebibytes.
+//
+// Parameters:
+//   - mib: The amount of memory (in MiB) to be used by the Argon2 algorithm. It is converted to
+//     KiB internally.
+//
+// Returns:
+//   - An Option that overrides the Memory field of the settings.
+func WithMemoryMiB(mib uint32) Option {
+	return func(o *options) {
+		o.settings.Memory = mibToKiB(mib)
+	}
+}
+
+// WithTime sets the time cost for the Argon2 hash generation as the number of iterations.
+//
+// Parameters:
+//   - time: The number of iterations (or passes) for Argon2.
+//
+// Returns:
+//   - An Option that overrides the Time field of the settings.
+func WithTime(time uint32) Option {
+	return func(o *options) {
+		o.settings.Time = time
+	}
+}
+
+// WithThreads sets the number of parallel threads for the Argon2 hash generation.
+//
+// Parameters:
+//   - threads: The number of parallel threads used during hashing.
+//
+// Returns:
+//   - An Option that overrides the Threads field of the settings.
+func WithThreads(threads uint8) Option {
+	return func(o *options) {
+		o.settings.Threads = threads
+	}
+}
+
+// WithSaltLength sets the length of the random salt in bytes.
+//
+// Parameters:
+//   - length: The length of the salt in bytes.
+//
+// Returns:
+//   - An Option that overrides the SaltLength field of the settings.
+func WithSaltLength(length uint32) Option {
+	return func(o *options) {
+		o.settings.SaltLength = length
+	}
+}
+
+// WithKeyLength sets the length of the derived key in bytes.
+//
+// Parameters:
+//   - length: The length of the derived key in bytes.
+//
+// Returns:
+//   - An Option that overrides the KeyLength field of the settings.
+func WithKeyLength(length uint32) Option {
+	return func(o *options) {
+		o.settings.KeyLength = length
+	}
+}
+
+// WithVariant sets the Argon2 variant that is used for the key derivation.
+//
+// Parameters:
+//   - variant: The Argon2 Variant to use.
+//
+// Returns:
+//   - An Option that overrides the Variant field of the settings.
+func WithVariant(variant Variant) Option {
+	return func(o *options) {
+		o.settings.Variant = variant
+	}
+}
+
+// WithZeroSaltCheck enables or disables the check for all-zero salts.
+//
+// By default, a generated salt that consists of zeros only is treated as a sign of a broken
+// random source. The salt is read once more and if it is still all zeros, the derivation fails
+// with ErrZeroSalt. Since a legitimate all-zero salt is astronomically unlikely, the check
+// should only be disabled when a deterministic random source is used on purpose.
+//
+// Parameters:
+//   - enabled: Whether all-zero salts are rejected.
+//
+// Returns:
+//   - An Option that enables or disables the all-zero salt check.
+func WithZeroSaltCheck(enabled bool) Option {
+	return func(o *options) {
+		o.allowZeroSalt = !enabled
+	}
+}
+
+// WithRejectEmptyPassword enables or disables the rejection of empty passwords.
+//
+// By default, an empty password is a valid input for the Argon2 KDF, so it results in a valid
+// hash that validates against an empty input. If enabled, the derivation of a hash for a
+// zero-length password fails with ErrEmptyPassword instead. This acts as a backstop against
+// application bugs that would otherwise store hashes of blank passwords.
+//
+// Parameters:
+//   - enabled: Whether empty passwords are rejected.
+//
+// Returns:
+//   - An Option that enables or disables the rejection of empty passwords.
+func WithRejectEmptyPassword(enabled bool) Option {
+	return func(o *options) {
+		o.rejectEmpty = enabled
+	}
+}
+
+// WithUnicodeNormalization normalizes the password to the given Unicode normalization form before
+// it is passed to the Argon2 KDF.
+//
+// The same typed password can be encoded differently depending on the platform, e.g. accented
+// characters are usually composed (NFC) on Windows and Linux, but may be decomposed (NFD) on
+// macOS. Without normalization, such a password fails to validate across platforms. The
+// normalization uses golang.org/x/text/unicode/norm, which adds a dependency on the Unicode
+// tables of golang.org/x/text to the binary. Since it changes the input of the KDF, it is opt-in.
+//
+// No flag is stored in the hash, so the same normalization must be applied consistently by
+// configuration when the hash is derived with DeriveWithOptions and when it is validated with
+// ValidateWithOptions. Enabling it for existing hashes of passwords that are not in the given
+// form makes them fail to validate.
+//
+// Parameters:
+//   - form: The Unicode normalization form to apply, e.g. norm.NFC.
+//
+// Returns:
+//   - An Option that enables the Unicode normalization of the password.
+func WithUnicodeNormalization(form norm.Form) Option {
+	return func(o *options) {
+		o.normalize = true
+		o.unicodeForm = form
+	}
+}
+
+// WithSkipEmptyLines enables or disables skipping empty lines in DeriveAll.
+//
+// By default, DeriveAll fails on an empty line, since deriving a hash for an empty password is
+// usually not intended in bulk operations. If enabled, empty lines are skipped instead and no
+// hash is returned for them.
+//
+// Parameters:
+//   - enabled: Whether empty lines are skipped.
+//
+// Returns:
+//   - An Option that enables or disables skipping empty lines.
+func WithSkipEmptyLines(enabled bool) Option {
+	return func(o *options) {
+		o.skipEmpty = enabled
+	}
+}
+
+// WithTimestamp enables or disables storing the creation timestamp in the derived hash.
+//
+// If enabled, the current time is appended to the hash as TimestampLength bytes of Unix time in
+// seconds and a flag is set in the serialized settings, so that the timestamp can be read back
+// with CreatedAt, e.g. to enforce password-aging policies without a separate database column.
+// The timestamp is not an input of the Argon2 KDF and is therefore not authenticated. Since it
+// changes the length of the hash, it is opt-in.
+//
+// Parameters:
+//   - enabled: Whether the creation timestamp is stored in the hash.
+//
+// Returns:
+//   - An Option that enables or disables storing the creation timestamp.
+func WithTimestamp(enabled bool) Option {
+	return func(o *options) {
+		o.timestamp = enabled
+	}
+}
+
+// WithTimestampAt enables storing the creation timestamp in the derived hash like WithTimestamp,
+// but stores the given time instead of the current time.
+//
+// This allows hashes that are migrated from another system to keep their original creation
+// time, and makes the timestamp reproducible in tests. Like with WithTimestamp, the time is
+// stored as Unix time in seconds, so fractions of a second are dropped.
+//
+// Parameters:
+//   - created: The creation time that is stored in the hash. If it is the zero time, the current
+//     time is stored.
+//
+// Returns:
+//   - An Option that enables storing the given creation timestamp.
+func WithTimestampAt(created time.Time) Option {
+	return func(o *options) {
+		o.timestamp = true
+		o.createdAt = created
+	}
+}
+
+// WithMaxPasswordLength sets the maximum password length in bytes that is accepted by
+// DeriveWithOptions and ValidateWithOptions.
+//
+// By default, passwords are limited to MaxPasswordLength bytes to prevent a denial of service
+// with huge passwords. Derivations of longer passwords fail with ErrPasswordTooLong and
+// validations of longer passwords fail after executing the Argon2 KDF on a truncated password.
+//
+// Parameters:
+//   - length: The maximum password length in bytes. A length of zero or less disables the check.
+//
+// Returns:
+//   - An Option that overrides the maximum password length.
+func WithMaxPasswordLength(length int) Option {
+	return func(o *options) {
+		o.maxPasswordLength = noPasswordLimit
+		if length > 0 {
+			o.maxPasswordLength = length
+		}
+	}
+}
+
+// DeriveWithOptions generates an Argon2 hash using the provided password and functional options.
+//
+// This function starts from DefaultSettings and applies the given options in order, overriding
+// the individual fields of the settings. The resulting settings are then used to derive the hash
+// via Derive. This keeps call sites readable when only a single parameter needs to be tweaked.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - opts: A list of Option values that override individual fields of DefaultSettings.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if any issues occur during salt generation or key derivation.
+func DeriveWithOptions(password string, opts ...Option) (Argon2, error) {
+	return derive([]byte(password), nil, newOptions(DefaultSettings, opts...), rand.Reader)
+}
+
+// ValidateWithOptions verifies whether the given password matches the Argon2 hash, applying the
+// password transformations of the given functional options, e.g. WithUnicodeNormalization.
+//
+// This is the counterpart to DeriveWithOptions. The hash is always validated with the settings
+// that are stored in the hash, so options that override the settings have no effect. All timing
+// attack mitigations of Validate apply.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//   - opts: A list of Option values that configure the password transformations.
+//
+// Returns:
+//   - true if the password is valid and matches the stored Argon2 hash.
+func (a Argon2) ValidateWithOptions(password string, opts ...Option) bool {
+	o := newOptions(DefaultSettings, opts...)
+	_, err := a.validateWithDummy(o.password([]byte(password)), nil, false, nil, o.maxPassword())
+	return err == nil
+}
+
+// newOptions returns the options that result from applying the given list of functional
+// options to the given base settings.
+func newOptions(settings Settings, opts ...Option) options {
+	o := options{settings: settings}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&o)
+	}
+	return o
+}
+
+// maxPassword returns the maximum password length of the options. A value of zero or less
+// disables the check.
+func (o options) maxPassword() int {
+	if o.maxPasswordLength == 0 {
+		return MaxPasswordLength
+	}
+	return o.maxPasswordLength
+}
+
+// created returns the creation time that is stored in timestamped hashes. It is the time of
+// WithTimestampAt or, if none was given, the current time.
+func (o options) created() time.Time {
+	if o.createdAt.IsZero() {
+		return time.Now()
+	}
+	return o.createdAt
+}
+
+// password applies the password transformations of the options to the given password.
+func (o options) password(password []byte) []byte {
+	if o.normalize {
+		password = o.unicodeForm.Bytes(password)
+	}
+	return password
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestDeriveWithOptions(t *testing.T) {
+	t.Run("derive with options overrides the default settings", func(t *testing.T) {
+		derived, err := DeriveWithOptions(testPassPhrase, WithMemory(testSettings.Memory),
+			WithTime(testSettings.Time), WithThreads(2), WithSaltLength(24), WithKeyLength(48),
+			WithVariant(VariantI))
+		if err != nil {
+			t.Fatalf("failed to derive hash with options: %s", err)
+		}
+		settings, err := SettingsFromBytes(derived[:SerializedSettingsLength])
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		want := Settings{
+			Memory:     testSettings.Memory,
+			Time:       testSettings.Time,
+			Threads:    2,
+			SaltLength: 24,
+			KeyLength:  48,
+			Variant:    VariantI,
+			Version:    DefaultSettings.Version,
+		}
+		if settings != want {
+			t.Errorf("derived settings are not as expected, got: %+v, want: %+v", settings, want)
+		}
+		if !derived.Validate(testPassPhrase) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+	t.Run("last option wins", func(t *testing.T) {
+		o := newOptions(DefaultSettings, WithTime(1), WithTime(3))
+		if o.settings.Time != 3 {
+			t.Errorf("time is not as expected, got: %d, want: %d", o.settings.Time, 3)
+		}
+	})
+	t.Run("memory in MiB is converted to KiB", func(t *testing.T) {
+		o := newOptions(DefaultSettings, WithMemoryMiB(128))
+		if o.settings.Memory != 128*1024 {
+			t.Errorf("memory is not as expected, got: %d, want: %d", o.settings.Memory, 128*1024)
+		}
+	})
+	t.Run("zero salt check is enabled by default", func(t *testing.T) {
+		if o := newOptions(DefaultSettings); o.allowZeroSalt {
+			t.Error("all-zero salts should be rejected by default")
+		}
+		if o := newOptions(DefaultSettings, WithZeroSaltCheck(false)); !o.allowZeroSalt {
+			t.Error("all-zero salts should be allowed when the check is disabled")
+		}
+	})
+	t.Run("disabled zero salt check allows all-zero salts", func(t *testing.T) {
+		o := newOptions(testFastSettings, WithZeroSaltCheck(false))
+		derived, err := derive([]byte(testPassPhrase), nil, o, zeroReader{})
+		if err != nil {
+			t.Fatalf("derive with disabled zero salt check should succeed, got: %s", err)
+		}
+		if !isZero(derived.Salt()) {
+			t.Errorf("salt should be all zeros, got: %x", derived.Salt())
+		}
+	})
+	t.Run("empty password is accepted by default", func(t *testing.T) {
+		derived, err := derive(nil, nil, newOptions(testFastSettings), rand.Reader)
+		if err != nil {
+			t.Fatalf("derive with empty password should succeed by default, got: %s", err)
+		}
+		if !derived.Validate("") {
+			t.Error("derived hash is not valid for the empty password")
+		}
+	})
+	t.Run("empty password is rejected when enabled", func(t *testing.T) {
+		o := newOptions(testFastSettings, WithRejectEmptyPassword(true))
+		if _, err := derive([]byte{}, nil, o, rand.Reader); !errors.Is(err, ErrEmptyPassword) {
+			t.Errorf("derive with empty password should fail with ErrEmptyPassword, got: %v", err)
+		}
+		if _, err := derive([]byte(testPassPhrase), nil, o, rand.Reader); err != nil {
+			t.Errorf("derive with non-empty password should succeed, got: %s", err)
+		}
+	})
+	t.Run("nil options are ignored", func(t *testing.T) {
+		o := newOptions(DefaultSettings, nil)
+		if o.settings != DefaultSettings {
+			t.Errorf("settings are not as expected, got: %+v, want: %+v", o.settings, DefaultSettings)
+		}
+	})
+	t.Run("derive with unsupported variant fails", func(t *testing.T) {
+		if _, err := DeriveWithOptions(testPassPhrase, WithVariant(VariantD)); err == nil {
+			t.Fatal("derive with unsupported variant should have failed")
+		}
+	})
+}
+
+func TestArgon2_ValidateWithOptions(t *testing.T) {
+	const composed, decomposed = "p\u00e4ssw\u00f6rd", "pa\u0308ssw\u00f6rd"
+
+	t.Run("normalized password validates across forms", func(t *testing.T) {
+		o := newOptions(testFastSettings, WithUnicodeNormalization(norm.NFC))
+		derived, err := derive([]byte(decomposed), nil, o, rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		for _, password := range []string{composed, decomposed} {
+			if !derived.ValidateWithOptions(password, WithUnicodeNormalization(norm.NFC)) {
+				t.Errorf("normalized password %q should be valid", password)
+			}
+		}
+		if derived.Validate(decomposed) {
+			t.Error("decomposed password should not be valid without normalization")
+		}
+	})
+	t.Run("password is not normalized by default", func(t *testing.T) {
+		derived, err := derive([]byte(decomposed), nil, newOptions(testFastSettings), rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateWithOptions(decomposed) {
+			t.Error("identical password should be valid")
+		}
+		if derived.ValidateWithOptions(composed) {
+			t.Error("differently encoded password should not be valid without normalization")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// DeriveWithSecret generates an Argon2 hash using the provided password, server-side secret
+// (pepper) and settings.
+//
+// The secret is mixed into the derivation by computing HMAC-SHA256 over the password, keyed with
+// the secret. The resulting 32 byte MAC is then used as the password input for the Argon2 KDF.
+// The secret itself is never stored in the hash, so an attacker who only obtains the stored hashes
+// cannot brute force them offline without also knowing the secret. Hashes created with a secret
+// can only be validated with ValidateWithSecret using the same secret.
+//
+// Parameters:
+//   - password: The password to derive the key from.
+//   - secret: The server-side secret. It must not be empty.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the secret is empty, ErrPasswordTooLong if the password exceeds
+//     MaxPasswordLength, or an error if any issues occur during the hash generation.
+func DeriveWithSecret(password string, secret []byte, settings Settings) (Argon2, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret must not be empty")
+	}
+	if err := checkPasswordLength([]byte(password), MaxPasswordLength); err != nil {
+		return nil, err
+	}
+	return DeriveBytes(pepper([]byte(password), secret), settings)
+}
+
+// ValidateWithSecret verifies whether the given password matches an Argon2 hash that was
+// created with DeriveWithSecret using the given server-side secret.
+//
+// The password is mixed with the secret in the same way as in DeriveWithSecret before it is
+// validated. All timing attack mitigations of Validate apply, including the rejection of
+// passwords that exceed MaxPasswordLength. If the secret is empty, the
+// Argon2 KDF is executed anyway and the validation fails.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//   - secret: The server-side secret that was used to create the hash.
+//
+// Returns:
+//   - true if the password is valid and matches the stored Argon2 hash.
+func (a Argon2) ValidateWithSecret(password string, secret []byte) bool {
+	peppered, tooLong := truncatePassword([]byte(password), MaxPasswordLength)
+	valid := a.ValidateBytes(pepper(peppered, secret))
+	return valid && len(secret) > 0 && !tooLong
+}
+
+// RepepperOnLogin validates the password with the old server-side secret and, if it is valid,
+// derives a new hash of the password with the new secret.
+//
+// Since the secret is mixed into the input of the Argon2 KDF, an existing hash cannot be moved to
+// a new secret without the plaintext password. The only safe way to rotate the secret is therefore
+// to re-derive the hash on a successful login:
+//
+//	hash, valid, err := stored.RepepperOnLogin(password, oldSecret, newSecret, argon2.DefaultSettings)
+//	if !valid {
+//		return errInvalidLogin
+//	}
+//	if err == nil {
+//		// store hash instead of stored
+//	}
+//
+// All timing attack mitigations of ValidateWithSecret apply. The existing hash is not modified.
+//
+// Parameters:
+//   - password: The plaintext password to validate against the Argon2 hash.
+//   - oldSecret: The server-side secret that was used to create the hash.
+//   - newSecret: The server-side secret to derive the new hash with. It must not be empty.
+//   - settings: A Settings struct containing parameters for the new Argon2 hash.
+//
+// Returns:
+//   - The newly derived Argon2 hash, or nil if the password is not valid or the derivation failed.
+//   - true if the password is valid with the old secret, false otherwise.
+//   - An error if the password is valid but the new hash cannot be derived, e.g. because the new
+//     secret is empty or the settings are invalid.
+func (a Argon2) RepepperOnLogin(password string, oldSecret, newSecret []byte, settings Settings) (Argon2, bool, error) {
+	if !a.ValidateWithSecret(password, oldSecret) {
+		return nil, false, nil
+	}
+	hash, err := DeriveWithSecret(password, newSecret, settings)
+	if err != nil {
+		return nil, true, err
+	}
+	return hash, true, nil
+}
+
+// pepper mixes the secret into the password by computing HMAC-SHA256 over the password, keyed
+// with the secret.
+func pepper(password, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(password)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testSecret = []byte("s3rv3r-s1d3-p3pp3r")
+
+func TestDeriveWithSecret(t *testing.T) {
+	t.Run("derive with secret succeeds", func(t *testing.T) {
+		derived, err := DeriveWithSecret(testPassPhrase, testSecret, testSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash with secret: %s", err)
+		}
+		if !derived.ValidateWithSecret(testPassPhrase, testSecret) {
+			t.Error("derived hash is not valid with the same secret")
+		}
+	})
+	t.Run("derive with too long password fails", func(t *testing.T) {
+		tooLong := strings.Repeat("a", MaxPasswordLength+1)
+		if _, err := DeriveWithSecret(tooLong, testSecret, testFastSettings); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("derive with too long password should have failed with ErrPasswordTooLong, got: %v", err)
+		}
+	})
+	t.Run("validate with too long password fails", func(t *testing.T) {
+		maxPassword := strings.Repeat("a", MaxPasswordLength)
+		derived, err := DeriveWithSecret(maxPassword, testSecret, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateWithSecret(maxPassword, testSecret) {
+			t.Error("derived hash is not valid with the maximum password length")
+		}
+		if derived.ValidateWithSecret(maxPassword+"a", testSecret) {
+			t.Error("hash should not be valid with a too long password")
+		}
+	})
+	t.Run("derive with empty secret fails", func(t *testing.T) {
+		if _, err := DeriveWithSecret(testPassPhrase, nil, testSettings); err == nil {
+			t.Fatal("derive with empty secret should have failed")
+		}
+	})
+}
+
+func TestArgon2_ValidateWithSecret(t *testing.T) {
+	derived, err := DeriveWithSecret(testPassPhrase, testSecret, testSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash with secret: %s", err)
+	}
+	t.Run("validate with wrong secret fails", func(t *testing.T) {
+		if derived.ValidateWithSecret(testPassPhrase, []byte("wrong-secret")) {
+			t.Error("hash is valid with a wrong secret")
+		}
+	})
+	t.Run("validate with empty secret fails", func(t *testing.T) {
+		if derived.ValidateWithSecret(testPassPhrase, nil) {
+			t.Error("hash is valid with an empty secret")
+		}
+	})
+	t.Run("validate with wrong password fails", func(t *testing.T) {
+		if derived.ValidateWithSecret("invalid", testSecret) {
+			t.Error("hash is valid with a wrong password")
+		}
+	})
+	t.Run("validate without secret fails", func(t *testing.T) {
+		if derived.Validate(testPassPhrase) {
+			t.Error("hash created with a secret is valid without the secret")
+		}
+	})
+	t.Run("validate unpeppered hash with secret fails", func(t *testing.T) {
+		if Argon2(testDerived).ValidateWithSecret(testPassPhrase, testSecret) {
+			t.Error("hash created without a secret is valid with a secret")
+		}
+	})
+}
+
+func TestArgon2_RepepperOnLogin(t *testing.T) {
+	newSecret := []byte("r0t4t3d-p3pp3r")
+	derived, err := DeriveWithSecret(testPassPhrase, testSecret, testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash with secret: %s", err)
+	}
+	t.Run("repepper with valid password succeeds", func(t *testing.T) {
+		repeppered, valid, err := derived.RepepperOnLogin(testPassPhrase, testSecret, newSecret, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to repepper hash: %s", err)
+		}
+		if !valid {
+			t.Fatal("password should be valid with the old secret")
+		}
+		if !repeppered.ValidateWithSecret(testPassPhrase, newSecret) {
+			t.Error("repeppered hash is not valid with the new secret")
+		}
+		if repeppered.ValidateWithSecret(testPassPhrase, testSecret) {
+			t.Error("repeppered hash should not be valid with the old secret")
+		}
+		if !derived.ValidateWithSecret(testPassPhrase, testSecret) {
+			t.Error("original hash should not be modified")
+		}
+	})
+	t.Run("repepper with wrong password fails", func(t *testing.T) {
+		repeppered, valid, err := derived.RepepperOnLogin("invalid", testSecret, newSecret, testFastSettings)
+		if err != nil {
+			t.Fatalf("repepper with wrong password should not return an error, got: %s", err)
+		}
+		if valid || repeppered != nil {
+			t.Error("repepper with wrong password should not be valid")
+		}
+	})
+	t.Run("repepper with wrong old secret fails", func(t *testing.T) {
+		_, valid, err := derived.RepepperOnLogin(testPassPhrase, newSecret, newSecret, testFastSettings)
+		if err != nil {
+			t.Fatalf("repepper with wrong secret should not return an error, got: %s", err)
+		}
+		if valid {
+			t.Error("repepper with wrong old secret should not be valid")
+		}
+	})
+	t.Run("repepper with empty new secret fails", func(t *testing.T) {
+		repeppered, valid, err := derived.RepepperOnLogin(testPassPhrase, testSecret, nil, testFastSettings)
+		if err == nil {
+			t.Fatal("repepper with empty new secret should have failed")
+		}
+		if !valid {
+			t.Error("password should still be reported as valid")
+		}
+		if repeppered != nil {
+			t.Error("repeppered hash should be nil on error")
+		}
+	})
+}
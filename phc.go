@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// phcLegacyVersion is the Argon2 version that is assumed for PHC strings without a version
+// segment. The version segment was only introduced with Argon2 version 1.3 (0x13), so hashes
+// without it were created with version 1.0 (0x10).
+const phcLegacyVersion = 0x10
+
+// phcPrefix is the common prefix of all Argon2 hashes in the PHC string format.
+const phcPrefix = "$argon2"
+
+// phcEncoding is the base64 encoding used for the salt and key in the PHC string format.
+var phcEncoding = base64.RawStdEncoding
+
+// MarshalPHC encodes the Argon2 hash into the PHC string format.
+//
+// The PHC string format is the de-facto standard representation for Argon2 hashes that is
+// used by the reference implementation and many other libraries. It has the following form:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+//
+// The salt and key are encoded using standard base64 encoding without padding. Keyed hashes that
+// were created with DeriveWithKey cannot be encoded, since the PHC format has no representation
+// for the keyed flag. Store them in the native byte format instead.
+//
+// The PHC format has no representation for the creation timestamp either. Timestamped hashes are
+// encoded without it, since the timestamp is not an input of the Argon2 KDF and the encoded hash
+// still validates the password. CreatedAt of a hash that was parsed back from the PHC string
+// therefore reports no creation time. This also applies to String, MarshalText and TextArgon2.
+// Use the native byte format or MarshalJSON to keep the creation timestamp.
+//
+// Returns:
+//   - The PHC string representation of the Argon2 hash.
+//   - An error if the Argon2 hash is structurally invalid, uses an unknown variant or is keyed.
+func (a Argon2) MarshalPHC() (string, error) {
+	layout, ok := parseLayout(a)
+	if !ok {
+		return "", fmt.Errorf("failed to encode Argon2 hash: %w", ErrInvalidHashLength)
+	}
+	settings := layout.settings
+	if settings.Variant > VariantD {
+		return "", fmt.Errorf("failed to encode Argon2 hash: unknown Argon2 variant: %d", settings.Variant)
+	}
+	if settings.Keyed {
+		return "", errors.New("failed to encode Argon2 hash: keyed hashes cannot be represented in the PHC format")
+	}
+
+	var builder strings.Builder
+	builder.WriteString("$")
+	builder.WriteString(settings.Variant.String())
+	builder.WriteString("$v=")
+	builder.WriteString(strconv.FormatUint(uint64(settings.version()), 10))
+	builder.WriteString("$m=")
+	builder.WriteString(strconv.FormatUint(uint64(settings.Memory), 10))
+	builder.WriteString(",t=")
+	builder.WriteString(strconv.FormatUint(uint64(settings.Time), 10))
+	builder.WriteString(",p=")
+	builder.WriteString(strconv.FormatUint(uint64(settings.Threads), 10))
+	builder.WriteString("$")
+	builder.WriteString(phcEncoding.EncodeToString(layout.salt(a)))
+	builder.WriteString("$")
+	builder.WriteString(phcEncoding.EncodeToString(layout.key(a)))
+	return builder.String(), nil
+}
+
+// ParsePHC decodes an Argon2 hash in the PHC string format into an Argon2 hash.
+//
+// This function is the counterpart to MarshalPHC. It parses the variant, version, parameters,
+// salt and key from the PHC string and assembles them into the native byte layout of this
+// package. The salt and key lengths are taken from the decoded values.
+//
+// The version segment is optional. Hashes that were created before Argon2 version 1.3 do not
+// have it, e.g. "$argon2id$m=65536,t=3,p=4$<salt>$<key>". For such hashes the Version of the
+// settings is set to 16 (0x10), so that the parsed version is preserved. Note that
+// golang.org/x/crypto/argon2 only implements version 19 (0x13), so hashes with version 16
+// can be parsed and encoded again, but validating them fails with ErrUnsupportedVersion.
+// Versions other than 16 and 19 do not exist and are rejected with ErrUnsupportedVersion.
+//
+// Parameters:
+//   - s: The PHC string, e.g. "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>".
+//
+// Returns:
+//   - The Argon2 hash in the native byte layout.
+//   - An error if the string is not a valid Argon2 PHC string or if the resulting settings are
+//     outside the allowed ranges.
+func ParsePHC(s string) (Argon2, error) {
+	parts := strings.Split(s, "$")
+	if (len(parts) != 5 && len(parts) != 6) || parts[0] != "" {
+		return nil, errors.New("failed to parse PHC string: invalid format")
+	}
+
+	variant, ok := parseVariant(parts[1])
+	if !ok {
+		return nil, fmt.Errorf("failed to parse PHC string: unknown algorithm: %q", parts[1])
+	}
+	settings := Settings{Variant: variant, Version: phcLegacyVersion}
+
+	// Strings with six parts have a version segment, strings with five parts predate it.
+	segments := parts[2:]
+	if len(parts) == 6 {
+		version, found := strings.CutPrefix(parts[2], "v=")
+		if !found {
+			return nil, fmt.Errorf("failed to parse PHC string: invalid version segment: %q", parts[2])
+		}
+		parsedVersion, err := strconv.ParseUint(version, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PHC string: invalid version: %w", err)
+		}
+		if parsedVersion != phcLegacyVersion && parsedVersion != argon2.Version {
+			return nil, fmt.Errorf("failed to parse PHC string: %w: %d", ErrUnsupportedVersion, parsedVersion)
+		}
+		settings.Version = uint8(parsedVersion)
+		segments = parts[3:]
+	}
+
+	if err := parsePHCParams(segments[0], &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse PHC string: %w", err)
+	}
+
+	salt, err := phcEncoding.DecodeString(segments[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PHC string: failed to decode salt: %w", err)
+	}
+	key, err := phcEncoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PHC string: failed to decode key: %w", err)
+	}
+	settings.SaltLength = uint32(len(salt))
+	settings.KeyLength = uint32(len(key))
+	if err = settings.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to parse PHC string: %w", err)
+	}
+
+	return newHash(settings, salt, key), nil
+}
+
+// EncodeReference encodes the Argon2 hash exactly like the `-e` output of the argon2 command line
+// utility of the reference implementation.
+//
+// The reference utility emits the variant, the version, the memory, time and parallelism
+// parameters in this order, followed by the salt and key in standard base64 encoding without
+// padding. This is the same layout that MarshalPHC produces, so the output can be compared
+// byte-by-byte with the output of the reference utility for the same password, salt and
+// parameters, e.g. to cross-check the interoperability in CI.
+//
+// Parameters:
+//   - a: The Argon2 hash to encode.
+//
+// Returns:
+//   - The encoded string as emitted by the reference utility.
+//   - An error if the Argon2 hash cannot be encoded, see MarshalPHC.
+func EncodeReference(a Argon2) (string, error) {
+	return a.MarshalPHC()
+}
+
+// Verify verifies whether the given password matches the stored hash, which can either be in
+// the PHC string format or in the native byte layout of this package.
+//
+// This function is meant for databases where both formats coexist, e.g. during a migration.
+// The format is detected by the prefix: if the stored hash starts with "$argon2", it is parsed
+// as PHC string using ParsePHC. Otherwise, the stored string is treated as raw hash bytes.
+//
+// Parameters:
+//   - stored: The stored hash, either as PHC string or as raw bytes in a string.
+//   - password: The plaintext password to validate against the stored hash.
+//
+// Returns:
+//   - true if the password matches the stored hash, false otherwise.
+//   - An error if the stored hash cannot be parsed or validated, e.g. because it is structurally
+//     invalid. A wrong password is not an error.
+func Verify(stored string, password string) (bool, error) {
+	hash := Argon2(stored)
+	if strings.HasPrefix(stored, phcPrefix) {
+		parsed, err := ParsePHC(stored)
+		if err != nil {
+			// Parsing errors are returned without executing the KDF, since the PHC string format
+			// is not secret and the error does not depend on the password.
+			return false, err
+		}
+		hash = parsed
+	}
+	switch err := hash.VerifyPassword(password); {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// String implements the fmt.Stringer interface and returns the PHC string representation of the
+// Argon2 hash. If the Argon2 hash is structurally invalid, "<invalid>" is returned.
+//
+// Security considerations:
+//   - The returned string contains the full salt and derived key. Anyone who obtains it can run
+//     an offline brute force attack against the password, just like with a leaked database row.
+//     Since fmt uses String for the %s and %v verbs, be careful when logging values or structs
+//     that contain an Argon2 hash.
+//   - Since fmt also uses String for the %x verb, use Hex to get the hex encoding of the raw bytes.
+func (a Argon2) String() string {
+	phc, err := a.MarshalPHC()
+	if err != nil {
+		return "<invalid>"
+	}
+	return phc
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The Argon2 hash is encoded in the
+// PHC string format using MarshalPHC. An empty Argon2 hash is encoded as an empty text.
+func (a Argon2) MarshalText() ([]byte, error) {
+	if len(a) == 0 {
+		return []byte{}, nil
+	}
+	phc, err := a.MarshalPHC()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(phc), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The text is parsed as PHC
+// string using ParsePHC. An empty text results in an empty Argon2 hash.
+func (a *Argon2) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = nil
+		return nil
+	}
+	hash, err := ParsePHC(string(text))
+	if err != nil {
+		return err
+	}
+	*a = hash
+	return nil
+}
+
+// parsePHCParams parses the comma-separated parameter segment of a PHC string into the given
+// settings. The memory (m), time (t) and parallelism (p) parameters are required.
+func parsePHCParams(segment string, settings *Settings) error {
+	var hasMemory, hasTime, hasThreads bool
+	for _, param := range strings.Split(segment, ",") {
+		name, value, found := strings.Cut(param, "=")
+		if !found {
+			return fmt.Errorf("invalid parameter: %q", param)
+		}
+		switch name {
+		case "m":
+			memory, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || hasMemory {
+				return fmt.Errorf("invalid memory parameter: %q", value)
+			}
+			settings.Memory, hasMemory = uint32(memory), true
+		case "t":
+			iterations, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || hasTime {
+				return fmt.Errorf("invalid time parameter: %q", value)
+			}
+			settings.Time, hasTime = uint32(iterations), true
+		case "p":
+			threads, err := strconv.ParseUint(value, 10, 8)
+			if err != nil || hasThreads {
+				return fmt.Errorf("invalid parallelism parameter: %q", value)
+			}
+			settings.Threads, hasThreads = uint8(threads), true
+		default:
+			return fmt.Errorf("unknown parameter: %q", name)
+		}
+	}
+	if !hasMemory || !hasTime || !hasThreads {
+		return errors.New("missing required parameter")
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const (
+	testPHC = "$argon2id$v=19$m=262144,t=1,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5+BDa9Pq1W2enBf7VIh37M"
+
+	// testReferencePHC has been produced by the reference implementation using:
+	// echo -n "password" | ./argon2 somesalt -t 2 -m 16 -p 4 -l 24
+	testReferencePHC = "$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+)
+
+func TestArgon2_MarshalPHC(t *testing.T) {
+	t.Run("marshal static values", func(t *testing.T) {
+		phc, err := Argon2(testDerived).MarshalPHC()
+		if err != nil {
+			t.Fatalf("failed to marshal PHC string: %s", err)
+		}
+		if phc != testPHC {
+			t.Errorf("PHC string is not as expected, got: %s, want: %s", phc, testPHC)
+		}
+	})
+	t.Run("marshal invalid hash fails", func(t *testing.T) {
+		if _, err := Argon2(testDerived[:10]).MarshalPHC(); !errors.Is(err, ErrInvalidHashLength) {
+			t.Fatalf("marshal of invalid hash should have failed with invalid hash length, got: %v", err)
+		}
+	})
+	t.Run("marshal timestamped hash drops the timestamp", func(t *testing.T) {
+		created := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+		derived, err := DeriveWithOptions(testPassPhrase, WithMemory(testFastSettings.Memory),
+			WithTime(testFastSettings.Time), WithThreads(testFastSettings.Threads), WithTimestampAt(created))
+		if err != nil {
+			t.Fatalf("failed to derive hash with timestamp: %s", err)
+		}
+		phc, err := derived.MarshalPHC()
+		if err != nil {
+			t.Fatalf("failed to marshal PHC string: %s", err)
+		}
+		parsed, err := ParsePHC(phc)
+		if err != nil {
+			t.Fatalf("failed to parse PHC string: %s", err)
+		}
+		if _, ok := parsed.CreatedAt(); ok {
+			t.Error("hash parsed from PHC string should not have a creation time")
+		}
+		if !parsed.Validate(testPassPhrase) {
+			t.Error("hash parsed from PHC string should still validate the password")
+		}
+	})
+	t.Run("marshal hash with unknown variant fails", func(t *testing.T) {
+		argon := bytes.Clone(testDerived)
+		argon[9] = 99
+		if _, err := Argon2(argon).MarshalPHC(); err == nil {
+			t.Fatal("marshal of hash with unknown variant should have failed")
+		}
+	})
+}
+
+func TestParsePHC(t *testing.T) {
+	t.Run("parse round-trips with marshal", func(t *testing.T) {
+		argon, err := ParsePHC(testPHC)
+		if err != nil {
+			t.Fatalf("failed to parse PHC string: %s", err)
+		}
+		if !bytes.Equal(argon.Salt(), Argon2(testDerived).Salt()) {
+			t.Errorf("parsed salt is not as expected, got: %x, want: %x", argon.Salt(), Argon2(testDerived).Salt())
+		}
+		if !bytes.Equal(argon.Key(), Argon2(testDerived).Key()) {
+			t.Errorf("parsed key is not as expected, got: %x, want: %x", argon.Key(), Argon2(testDerived).Key())
+		}
+		phc, err := argon.MarshalPHC()
+		if err != nil {
+			t.Fatalf("failed to marshal PHC string: %s", err)
+		}
+		if phc != testPHC {
+			t.Errorf("PHC string is not as expected, got: %s, want: %s", phc, testPHC)
+		}
+		if !argon.Validate(testPassPhrase) {
+			t.Error("parsed hash is not valid but should be")
+		}
+	})
+	t.Run("parse reference implementation output", func(t *testing.T) {
+		argon, err := ParsePHC(testReferencePHC)
+		if err != nil {
+			t.Fatalf("failed to parse PHC string: %s", err)
+		}
+		if !argon.Validate("password") {
+			t.Error("reference hash is not valid but should be")
+		}
+	})
+	t.Run("parse string without version segment", func(t *testing.T) {
+		input := "$argon2i$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+		argon, err := ParsePHC(input)
+		if err != nil {
+			t.Fatalf("failed to parse PHC string: %s", err)
+		}
+		settings, err := argon.Settings()
+		if err != nil {
+			t.Fatalf("failed to extract settings: %s", err)
+		}
+		if settings.Version != 0x10 {
+			t.Errorf("parsed version is not as expected, got: %d, want: %d", settings.Version, 0x10)
+		}
+		if settings.Variant != VariantI || settings.Memory != 65536 || settings.Time != 2 || settings.Threads != 4 {
+			t.Errorf("parsed settings are not as expected, got: %+v", settings)
+		}
+		phc, err := argon.MarshalPHC()
+		if err != nil {
+			t.Fatalf("failed to marshal PHC string: %s", err)
+		}
+		want := "$argon2i$v=16$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+		if phc != want {
+			t.Errorf("PHC string is not as expected, got: %s, want: %s", phc, want)
+		}
+		if err = argon.VerifyPassword("password"); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("verification of version 16 hash should fail with unsupported version, got: %v", err)
+		}
+	})
+	t.Run("parse unknown version fails", func(t *testing.T) {
+		_, err := ParsePHC("$argon2i$v=18$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG")
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("parsing unknown version should fail with unsupported version, got: %v", err)
+		}
+	})
+	t.Run("parse excessive costs fails", func(t *testing.T) {
+		for _, input := range []string{
+			"$argon2id$v=19$m=4294967295,t=1,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5",
+			"$argon2id$v=19$m=65536,t=4294967295,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5",
+		} {
+			var settingErr *InvalidSettingError
+			if _, err := ParsePHC(input); !errors.As(err, &settingErr) {
+				t.Errorf("parsing %q should fail with an InvalidSettingError, got: %v", input, err)
+			}
+		}
+	})
+	t.Run("parse invalid strings fails", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input string
+		}{
+			{"empty string", ""},
+			{"missing segments", "$argon2id$v=19$m=65536,t=1,p=4$8uCfrPniGiJolKHZe9bP+w"},
+			{"unknown algorithm", "$scrypt$v=19$m=65536,t=1,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5"},
+			{"invalid version segment", "$argon2id$x=19$m=65536,t=1,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5"},
+			{"too many segments", "$argon2id$v=19$m=65536,t=1,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5$x"},
+			{"invalid version", "$argon2id$v=abc$m=65536,t=1,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5"},
+			{"missing parameter", "$argon2id$v=19$m=65536,t=1$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5"},
+			{"duplicate parameter", "$argon2id$v=19$m=65536,m=1,p=4$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5"},
+			{"unknown parameter", "$argon2id$v=19$m=65536,t=1,p=4,x=1$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5"},
+			{"threads overflow", "$argon2id$v=19$m=65536,t=1,p=256$8uCfrPniGiJolKHZe9bP+w$rr12IYDBfIe9vVep77LJt5"},
+			{"invalid salt", "$argon2id$v=19$m=65536,t=1,p=4$!!!$rr12IYDBfIe9vVep77LJt5"},
+			{"invalid key", "$argon2id$v=19$m=65536,t=1,p=4$8uCfrPniGiJolKHZe9bP+w$!!!"},
+			{"too short salt", "$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$rr12IYDBfIe9vVep77LJt5"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := ParsePHC(tt.input); err == nil {
+					t.Errorf("parsing %q should have failed", tt.input)
+				}
+			})
+		}
+	})
+}
+
+func TestEncodeReference(t *testing.T) {
+	t.Run("encoding matches the reference utility", func(t *testing.T) {
+		settings := Settings{Memory: 65536, Time: 2, Threads: 4, KeyLength: 24, Variant: VariantI}
+		derived, err := DeriveWithSalt("password", []byte("somesalt"), settings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		encoded, err := EncodeReference(derived)
+		if err != nil {
+			t.Fatalf("failed to encode hash: %s", err)
+		}
+		if encoded != testReferencePHC {
+			t.Errorf("encoded hash is not as expected, got: %s, want: %s", encoded, testReferencePHC)
+		}
+	})
+	t.Run("encoding invalid hash fails", func(t *testing.T) {
+		if _, err := EncodeReference(Argon2(testDerived[:len(testDerived)-2])); err == nil {
+			t.Error("encoding invalid hash should have failed")
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("verify PHC string", func(t *testing.T) {
+		valid, err := Verify(testPHC, testPassPhrase)
+		if err != nil {
+			t.Fatalf("failed to verify PHC string: %s", err)
+		}
+		if !valid {
+			t.Error("PHC string should be valid")
+		}
+	})
+	t.Run("verify native hash", func(t *testing.T) {
+		valid, err := Verify(string(testDerived), testPassPhrase)
+		if err != nil {
+			t.Fatalf("failed to verify native hash: %s", err)
+		}
+		if !valid {
+			t.Error("native hash should be valid")
+		}
+	})
+	t.Run("verify with wrong password", func(t *testing.T) {
+		for _, stored := range []string{testReferencePHC, string(testDerived)} {
+			valid, err := Verify(stored, "wrong password")
+			if err != nil {
+				t.Fatalf("verification with wrong password should not fail, got: %s", err)
+			}
+			if valid {
+				t.Error("verification with wrong password should not be valid")
+			}
+		}
+	})
+	t.Run("verify invalid PHC string fails", func(t *testing.T) {
+		valid, err := Verify("$argon2id$invalid", testPassPhrase)
+		if err == nil || valid {
+			t.Errorf("verification of invalid PHC string should fail, got: %t, %v", valid, err)
+		}
+	})
+	t.Run("verify invalid native hash fails", func(t *testing.T) {
+		valid, err := Verify(string(testDerived[:len(testDerived)-2]), testPassPhrase)
+		if !errors.Is(err, ErrInvalidHash) || valid {
+			t.Errorf("verification of invalid native hash should fail, got: %t, %v", valid, err)
+		}
+	})
+}
+
+func TestArgon2_String(t *testing.T) {
+	t.Run("string returns the PHC representation", func(t *testing.T) {
+		if str := Argon2(testDerived).String(); str != testPHC {
+			t.Errorf("string is not as expected, got: %s, want: %s", str, testPHC)
+		}
+		if str := fmt.Sprintf("%s", Argon2(testDerived)); str != testPHC {
+			t.Errorf("formatted string is not as expected, got: %s, want: %s", str, testPHC)
+		}
+	})
+	t.Run("string of invalid hash", func(t *testing.T) {
+		if str := Argon2(testDerived[:10]).String(); str != "<invalid>" {
+			t.Errorf("string is not as expected, got: %s, want: %s", str, "<invalid>")
+		}
+	})
+}
+
+func TestArgon2_MarshalText(t *testing.T) {
+	t.Run("text round-trips through unmarshal", func(t *testing.T) {
+		text, err := Argon2(testDerived).MarshalText()
+		if err != nil {
+			t.Fatalf("failed to marshal text: %s", err)
+		}
+		if string(text) != testPHC {
+			t.Errorf("text is not as expected, got: %s, want: %s", text, testPHC)
+		}
+		var decoded Argon2
+		if err = decoded.UnmarshalText(text); err != nil {
+			t.Fatalf("failed to unmarshal text: %s", err)
+		}
+		if !decoded.Validate(testPassPhrase) {
+			t.Error("unmarshalled hash is not valid but should be")
+		}
+	})
+	t.Run("empty hash marshals to empty text", func(t *testing.T) {
+		var argon Argon2
+		text, err := argon.MarshalText()
+		if err != nil {
+			t.Fatalf("failed to marshal empty hash: %s", err)
+		}
+		if len(text) != 0 {
+			t.Errorf("text of empty hash is not empty, got: %s", text)
+		}
+	})
+	t.Run("invalid hash fails to marshal", func(t *testing.T) {
+		if _, err := Argon2(testDerived[:10]).MarshalText(); err == nil {
+			t.Fatal("marshal of invalid hash should have failed")
+		}
+	})
+}
+
+func TestArgon2_UnmarshalText(t *testing.T) {
+	t.Run("unmarshal empty text", func(t *testing.T) {
+		argon := Argon2(testDerived)
+		if err := argon.UnmarshalText([]byte{}); err != nil {
+			t.Fatalf("failed to unmarshal empty text: %s", err)
+		}
+		if argon != nil {
+			t.Error("hash is not nil after unmarshalling empty text")
+		}
+	})
+	t.Run("unmarshal invalid text fails", func(t *testing.T) {
+		var argon Argon2
+		if err := argon.UnmarshalText([]byte("invalid")); err == nil {
+			t.Fatal("unmarshal of invalid text should have failed")
+		}
+	})
+}
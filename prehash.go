@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// MaxPrehashedLength is the maximum length in bytes of a pre-hashed password that is accepted by
+// DerivePrehashed and ValidatePrehashed. It is the size of a SHA-512 digest, the largest digest
+// of the common hash functions. A longer input is not a digest and is rejected, so that a client
+// cannot feed arbitrarily large input into the Argon2 KDF.
+const MaxPrehashedLength = 64
+
+// DerivePrehashed generates an Argon2 hash using the provided pre-hashed password and settings.
+//
+// Some clients do not transmit the plaintext password, but a digest of it, e.g. its SHA-256
+// hash. Such a digest is binary key material and is passed to the Argon2 KDF as is. Unlike a
+// password, it must not be converted to or from a string, since a digest may contain arbitrary
+// bytes, including NUL bytes that truncate the value in other languages or C libraries. No
+// password transformations, like a Unicode normalization, are applied. Hashes created from a
+// pre-hashed password must be validated with ValidatePrehashed using the same kind of digest.
+// Since a digest is of fixed length, it is not subject to MaxPasswordLength, but must not be
+// longer than MaxPrehashedLength.
+//
+// Parameters:
+//   - prehashed: The pre-hashed password as raw bytes. It must not be empty.
+//   - settings: A Settings struct containing parameters for Argon2 hash generation.
+//
+// Returns:
+//   - A byte slice containing the concatenated serialized settings, salt, and derived key.
+//   - An error if the pre-hashed password is empty, ErrPasswordTooLong if it exceeds
+//     MaxPrehashedLength, or an error if any issues occur during the hash generation.
+func DerivePrehashed(prehashed []byte, settings Settings) (Argon2, error) {
+	if len(prehashed) == 0 {
+		return nil, errors.New("pre-hashed password must not be empty")
+	}
+	return derive(prehashed, nil, options{settings: settings, maxPasswordLength: MaxPrehashedLength}, rand.Reader)
+}
+
+// ValidatePrehashed verifies whether the given pre-hashed password matches an Argon2 hash that
+// was created with DerivePrehashed.
+//
+// The pre-hashed password is passed to the Argon2 KDF as raw bytes. All timing attack
+// mitigations of Validate apply. If the pre-hashed password is empty or exceeds
+// MaxPrehashedLength, the Argon2 KDF is executed anyway and the validation fails.
+//
+// Parameters:
+//   - prehashed: The pre-hashed password as raw bytes.
+//
+// Returns:
+//   - true if the pre-hashed password is valid and matches the stored Argon2 hash.
+func (a Argon2) ValidatePrehashed(prehashed []byte) bool {
+	_, err := a.validateWithDummy(prehashed, nil, false, nil, MaxPrehashedLength)
+	return err == nil && len(prehashed) > 0
+}
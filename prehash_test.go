@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"testing"
+)
+
+func TestDerivePrehashed(t *testing.T) {
+	t.Run("derive with pre-hashed password succeeds", func(t *testing.T) {
+		digest := sha256.Sum256([]byte(testPassPhrase))
+		derived, err := DerivePrehashed(digest[:], testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from pre-hashed password: %s", err)
+		}
+		if !derived.ValidatePrehashed(digest[:]) {
+			t.Error("derived hash is not valid with the same digest")
+		}
+	})
+	t.Run("derive with SHA-512 digest succeeds", func(t *testing.T) {
+		digest := sha512.Sum512([]byte(testPassPhrase))
+		derived, err := DerivePrehashed(digest[:], testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from SHA-512 digest: %s", err)
+		}
+		if !derived.ValidatePrehashed(digest[:]) {
+			t.Error("derived hash is not valid with the same SHA-512 digest")
+		}
+	})
+	t.Run("derive with oversized pre-hashed password fails", func(t *testing.T) {
+		digest := bytes.Repeat([]byte{0xab}, MaxPrehashedLength+1)
+		if _, err := DerivePrehashed(digest, testFastSettings); !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("derive with oversized pre-hashed password should fail with ErrPasswordTooLong, got: %v", err)
+		}
+	})
+	t.Run("derive with empty pre-hashed password fails", func(t *testing.T) {
+		if _, err := DerivePrehashed(nil, testFastSettings); err == nil {
+			t.Fatal("derive with empty pre-hashed password should have failed")
+		}
+	})
+}
+
+func TestArgon2_ValidatePrehashed(t *testing.T) {
+	digest := []byte{0x00, 0x01, 0x00, 0x02, 0xff, 0x00}
+	derived, err := DerivePrehashed(digest, testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash from pre-hashed password: %s", err)
+	}
+	t.Run("validate digest with NUL bytes succeeds", func(t *testing.T) {
+		if !derived.ValidatePrehashed(digest) {
+			t.Error("hash is not valid with the same digest")
+		}
+	})
+	t.Run("validate truncated digest fails", func(t *testing.T) {
+		if derived.ValidatePrehashed(digest[:1]) {
+			t.Error("hash is valid with a digest truncated at the first NUL byte")
+		}
+	})
+	t.Run("validate with empty digest fails", func(t *testing.T) {
+		if derived.ValidatePrehashed(nil) {
+			t.Error("hash is valid with an empty digest")
+		}
+	})
+	t.Run("validate with oversized digest fails", func(t *testing.T) {
+		oversized := append(bytes.Clone(digest), make([]byte, MaxPrehashedLength)...)
+		if derived.ValidatePrehashed(oversized) {
+			t.Error("hash is valid with an oversized digest")
+		}
+		long := bytes.Repeat([]byte{0xab}, MaxPrehashedLength+1)
+		hash, err := DerivePrehashed(long[:MaxPrehashedLength], testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash from pre-hashed password: %s", err)
+		}
+		if hash.ValidatePrehashed(long) {
+			t.Error("hash is valid with a digest that matches after truncation")
+		}
+	})
+	t.Run("validate with different digest fails", func(t *testing.T) {
+		other := sha256.Sum256([]byte("invalid"))
+		if derived.ValidatePrehashed(other[:]) {
+			t.Error("hash is valid with a different digest")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// secureBytesInitialSize is the initial buffer size of NewSecureBytesFromReader.
+const secureBytesInitialSize = 64
+
+// SecureBytes holds a plaintext password or secret that should be wiped from memory as soon as
+// it is no longer needed.
+//
+// Since strings are immutable in Go, a password that is passed around as a string cannot be
+// wiped. SecureBytes documents the intent of a caller-owned password buffer and provides Wipe,
+// so that the plaintext lifetime can be limited with a defer:
+//
+//	password, err := argon2.NewSecureBytesFromReader(os.Stdin)
+//	if err != nil {
+//		return err
+//	}
+//	defer password.Wipe()
+//	hash, err := argon2.DeriveBytes(password, argon2.DefaultSettings)
+//
+// SecureBytes can be passed to all functions that accept a password as byte slice, e.g.
+// DeriveBytes and ValidateBytes, without a conversion. Converting it to a string creates a copy
+// that is not wiped.
+type SecureBytes []byte
+
+// NewSecureBytesFromReader reads a password or secret from the provided io.Reader into a
+// SecureBytes buffer.
+//
+// The data is used verbatim, so a trailing newline is part of the secret. Unlike io.ReadAll,
+// every intermediate buffer that is discarded while the buffer grows is wiped, so that no copies
+// of the secret are left behind. On error, the data that has been read so far is wiped as well.
+//
+// Parameters:
+//   - r: The io.Reader to read the secret from. At most MaxReaderSecretLength bytes are accepted.
+//
+// Returns:
+//   - The secret in a SecureBytes buffer, which should be wiped by the caller after use.
+//   - An error if reading fails or if the secret exceeds MaxReaderSecretLength.
+func NewSecureBytesFromReader(r io.Reader) (SecureBytes, error) {
+	r = io.LimitReader(r, MaxReaderSecretLength+1)
+	buf := make(SecureBytes, 0, secureBytesInitialSize)
+	for {
+		if len(buf) == cap(buf) {
+			grown := make(SecureBytes, len(buf), 2*cap(buf))
+			copy(grown, buf)
+			buf.Wipe()
+			buf = grown
+		}
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if len(buf) > MaxReaderSecretLength {
+			buf.Wipe()
+			return nil, fmt.Errorf("secret exceeds the maximum length of %d bytes", MaxReaderSecretLength)
+		}
+		if errors.Is(err, io.EOF) {
+			return buf, nil
+		}
+		if err != nil {
+			buf.Wipe()
+			return nil, fmt.Errorf("failed to read secret: %w", err)
+		}
+	}
+}
+
+// Wipe overwrites the SecureBytes with zeros, including any spare capacity of the backing array.
+//
+// Like Argon2.Zero, all values that share the same backing array are wiped as well. After
+// calling Wipe, the SecureBytes only contain zeros.
+func (s SecureBytes) Wipe() {
+	data := s[:cap(s)]
+	for i := range data {
+		data[i] = 0
+	}
+	runtime.KeepAlive(data)
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewSecureBytesFromReader(t *testing.T) {
+	t.Run("read password succeeds", func(t *testing.T) {
+		password, err := NewSecureBytesFromReader(strings.NewReader(testPassPhrase))
+		if err != nil {
+			t.Fatalf("failed to read password: %s", err)
+		}
+		if string(password) != testPassPhrase {
+			t.Errorf("password is not as expected, got: %q, want: %q", password, testPassPhrase)
+		}
+	})
+	t.Run("read password larger than the initial buffer succeeds", func(t *testing.T) {
+		secret := bytes.Repeat([]byte("0123456789"), 100)
+		password, err := NewSecureBytesFromReader(bytes.NewReader(secret))
+		if err != nil {
+			t.Fatalf("failed to read password: %s", err)
+		}
+		if !bytes.Equal(password, secret) {
+			t.Error("password does not match the read secret")
+		}
+	})
+	t.Run("read empty password succeeds", func(t *testing.T) {
+		password, err := NewSecureBytesFromReader(strings.NewReader(""))
+		if err != nil {
+			t.Fatalf("failed to read password: %s", err)
+		}
+		if len(password) != 0 {
+			t.Errorf("password should be empty, got: %q", password)
+		}
+	})
+	t.Run("read too long secret fails", func(t *testing.T) {
+		secret := make([]byte, MaxReaderSecretLength+1)
+		if _, err := NewSecureBytesFromReader(bytes.NewReader(secret)); err == nil {
+			t.Fatal("read of too long secret should have failed")
+		}
+	})
+	t.Run("read with broken reader fails", func(t *testing.T) {
+		reader := io.MultiReader(strings.NewReader(testPassPhrase), failReader{})
+		_, err := NewSecureBytesFromReader(reader)
+		if err == nil {
+			t.Fatal("read with broken reader should have failed")
+		}
+		if errors.Is(err, io.EOF) {
+			t.Errorf("read with broken reader should not report EOF, got: %s", err)
+		}
+	})
+}
+
+func TestSecureBytes_Wipe(t *testing.T) {
+	t.Run("wipe zeroes the password", func(t *testing.T) {
+		password := SecureBytes(testPassPhrase)
+		password.Wipe()
+		if !isZero(password) {
+			t.Errorf("password is not wiped, got: %x", []byte(password))
+		}
+	})
+	t.Run("wipe zeroes the spare capacity", func(t *testing.T) {
+		password := SecureBytes(testPassPhrase)
+		short := password[:4]
+		short.Wipe()
+		if !isZero(password) {
+			t.Errorf("spare capacity is not wiped, got: %x", []byte(password))
+		}
+	})
+	t.Run("wipe of nil value does not panic", func(t *testing.T) {
+		var password SecureBytes
+		password.Wipe()
+	})
+	t.Run("secure bytes derive and validate", func(t *testing.T) {
+		password := SecureBytes(testPassPhrase)
+		defer password.Wipe()
+		derived, err := DeriveBytes(password, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if !derived.ValidateBytes(password) {
+			t.Error("derived hash is not valid but should be")
+		}
+	})
+}
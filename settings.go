@@ -6,8 +6,65 @@ package argon2
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Variant represents the Argon2 algorithm variant used for the key derivation.
+//
+// The variant is stored in the serialized settings in the byte that was previously used as the
+// (always zero) high byte of the Threads field. VariantID has the value 0, so hashes that were
+// serialized before variants were introduced are deserialized as Argon2id without any changes.
+type Variant uint8
+
+const (
+	// VariantID represents the Argon2id variant. This is the default and recommended variant.
+	VariantID Variant = iota
+
+	// VariantI represents the Argon2i variant. It is mainly provided for compatibility with
+	// legacy systems that used plain Argon2i.
+	VariantI
+
+	// VariantD represents the Argon2d variant. The variant can be represented in the settings,
+	// but golang.org/x/crypto/argon2 does not provide an implementation for it, so deriving or
+	// validating an Argon2d hash is not supported.
+	VariantD
 )
 
+// String returns the name of the Variant as it is used in the PHC string format, e.g. "argon2id".
+func (v Variant) String() string {
+	switch v {
+	case VariantID:
+		return "argon2id"
+	case VariantI:
+		return "argon2i"
+	case VariantD:
+		return "argon2d"
+	default:
+		return fmt.Sprintf("Variant(%d)", uint8(v))
+	}
+}
+
+// supported reports whether hashes of the Variant can be derived and validated.
+func (v Variant) supported() bool {
+	return v == VariantID || v == VariantI
+}
+
+// parseVariant returns the Variant for the given name as it is used in the PHC string format.
+// It returns false if the name does not belong to a known variant.
+func parseVariant(name string) (Variant, bool) {
+	for _, variant := range []Variant{VariantID, VariantI, VariantD} {
+		if variant.String() == name {
+			return variant, true
+		}
+	}
+	return 0, false
+}
+
 // Settings holds the configuration for generating an Argon2 hash.
 //
 // This struct contains the parameters required for Argon2 hashing, including memory cost,
@@ -22,48 +79,232 @@ import (
 //     the computation time for generating or validating the hash.
 //   - Threads: The number of parallel threads to use during the hash computation. This affects
 //     the speed of the hash calculation but also impacts performance based on the hardware.
+//     Threads is the parallelism parameter p of Argon2, which determines the number of lanes of
+//     the memory matrix. The derived key therefore depends on it: a hash derived with p lanes
+//     can only be validated with p lanes, so Threads cannot be lowered for an existing hash to
+//     save CPU. The key does not depend on how the lanes are scheduled, but
+//     golang.org/x/crypto/argon2 always processes the lanes with p goroutines and offers no
+//     single-threaded mode. To limit the CPU usage, cap the number of concurrent calls, e.g.
+//     with WithConcurrencyLimit, instead.
 //   - SaltLength: The length of the random salt in bytes. The salt is used to ensure that
 //     the same password results in different hashes when hashed multiple times with different salts.
 //   - KeyLength: The length of the derived key in bytes. This is the length of the hash output
 //     that will be used as the final result after Argon2 computation.
+//   - Variant: The Argon2 variant to use for the key derivation. The zero value is VariantID.
+//   - Version: The Argon2 algorithm version. The zero value is treated as the current version
+//     implemented by golang.org/x/crypto/argon2 (0x13/19).
+//   - Keyed: Whether the hash was derived with a secret key using DeriveWithKey. It is set by
+//     the package when a hash is derived and is ignored when passed to the Derive functions.
+//   - Timestamped: Whether the hash carries its creation timestamp, see WithTimestamp. It is set
+//     by the package when a hash is derived and is ignored when passed to the Derive functions.
 type Settings struct {
-	Memory     uint32
-	Time       uint32
-	Threads    uint8
-	SaltLength uint32
-	KeyLength  uint32
+	Memory      uint32
+	Time        uint32
+	Threads     uint8
+	SaltLength  uint32
+	KeyLength   uint32
+	Variant     Variant
+	Version     uint8
+	Keyed       bool
+	Timestamped bool
 }
 
+const (
+	// MinThreads is the minimum number of parallel threads that is accepted by Settings.Validate.
+	MinThreads = 1
+
+	// MinTime is the minimum number of iterations that is accepted by Settings.Validate.
+	MinTime = 1
+
+	// MinMemoryPerThread is the minimum amount of memory in kilobytes per thread that is accepted
+	// by Settings.Validate. The Argon2 specification requires at least 8 KiB of memory per lane.
+	MinMemoryPerThread = 8
+
+	// MinSaltLength is the minimum salt length in bytes that is accepted by Settings.Validate.
+	MinSaltLength = 8
+
+	// MinKeyLength is the minimum key length in bytes that is accepted by Settings.Validate.
+	MinKeyLength = 4
+
+	// MaxMemory is the maximum amount of memory in kilobytes that is accepted by Settings.Validate
+	// and when decoding stored hashes. It amounts to 4 GiB and rejects settings that cannot be
+	// legitimate, e.g. from corrupted headers. It is a format limit, not a denial of service limit:
+	// a crafted hash within the bounds can still make a single validation allocate 4 GiB and, with
+	// MaxTime, run for minutes. If stored hashes can be supplied by untrusted parties, check their
+	// Settings against the policy of the application before validating them, and cap the number
+	// of concurrent validations with a Hasher and WithConcurrencyLimit.
+	MaxMemory = 4 * 1024 * 1024
+
+	// MaxTime is the maximum number of iterations that is accepted by Settings.Validate and when
+	// decoding stored hashes. Like MaxMemory, it is a format limit that rejects settings that
+	// cannot be legitimate, not a bound on the duration of a validation.
+	MaxTime = 1024
+
+	// MaxSaltLength is the maximum salt length in bytes that is accepted by Settings.Validate and
+	// Scan. It prevents crafted settings from causing huge allocations.
+	MaxSaltLength = 1024
+
+	// MaxKeyLength is the maximum key length in bytes that is accepted by Settings.Validate and
+	// Scan. It prevents crafted settings from causing huge allocations.
+	MaxKeyLength = 1024
+
+	// RecommendedSaltLength is the minimum recommended salt length in bytes. Shorter salts are
+	// reported by Settings.ValidateWithStrictness and rejected in StrictSettings mode.
+	RecommendedSaltLength = 16
+
+	// RecommendedKeyLength is the minimum recommended key length in bytes. Shorter keys are
+	// reported by Settings.ValidateWithStrictness and rejected in StrictSettings mode.
+	RecommendedKeyLength = 16
+)
+
+// Strictness defines how settings that are valid, but below the recommended salt and key lengths,
+// are handled by Settings.ValidateWithStrictness and by a Hasher that was configured with
+// WithStrictness.
+type Strictness uint8
+
+const (
+	// LenientSettings accepts salts and keys below RecommendedSaltLength and RecommendedKeyLength
+	// for backward compatibility and reports them as warnings. This is the default.
+	LenientSettings Strictness = iota
+
+	// StrictSettings rejects salts and keys below RecommendedSaltLength and RecommendedKeyLength
+	// with an *InvalidSettingError.
+	StrictSettings
+)
+
+// keyedFlag is set in the serialized variant byte for hashes that were derived with a secret key
+// using DeriveWithKey. The variant itself only occupies the lower bits of the byte.
+const keyedFlag = 0x80
+
+// timestampedFlag is set in the serialized variant byte for hashes that carry their creation
+// timestamp as TimestampLength bytes after the derived key.
+const timestampedFlag = 0x40
+
+// TimestampLength is the length in bytes of the creation timestamp that is appended to hashes
+// that were derived using WithTimestamp. The timestamp is stored as Unix time in seconds.
+const TimestampLength = 8
+
+// flagMask covers all flags that are stored in the serialized variant byte.
+const flagMask = keyedFlag | timestampedFlag
+
+// SerializedFormatVersion is the format-version byte that prefixes the serialized Settings. It
+// allows future changes to the serialized layout to be detected and migrated. Settings that were
+// serialized before the format-version byte was introduced are referred to as format version 0.
+const SerializedFormatVersion = 0x01
+
 // SerializedSettingsLength defines the fixed size in bytes required to serialize the Settings struct using
-// little-endian encoding.
-const SerializedSettingsLength = 18
+// little-endian encoding, including the leading format-version byte. It is the single canonical constant
+// for the size of the settings header and is used throughout the package for all offset calculations.
+const SerializedSettingsLength = 20
 
-// DefaultSettings is the default configuration for Argon2 hashing.
+// UnversionedSerializedSettingsLength defines the size in bytes of the serialized Settings in format
+// version 0, which were written before the format-version byte was introduced.
 //
-// This variable provides default values for the `Settings` struct that can be used
-// in hash derivation and validation when custom settings are not specified. The values
-// are chosen to provide a reasonable balance between security and performance, suitable
-// for most general use cases.
+// Format version 0 hashes are read transparently. Since the salt and key lengths are read from the
+// header, they are told apart from current hashes by the leading format-version byte in combination
+// with the total length of the data. Use UpgradeHash to migrate them to the current format.
+const UnversionedSerializedSettingsLength = 19
+
+// LegacySerializedSettingsLength defines the size in bytes of the serialized Settings that were written
+// before the Argon2 version was included in the serialized format.
+//
+// Legacy hashes only differ from format version 0 hashes by the missing trailing version byte in the
+// settings header. Since the salt and key lengths are read from the header, the two formats can be told
+// apart by their total length. Legacy hashes are therefore read transparently and are treated as Argon2
+// version 0x13, which is the only version golang.org/x/crypto/argon2 has ever implemented. To migrate
+// a legacy hash to the current format, use UpgradeHash or simply re-derive the hash on the next
+// successful login.
+const LegacySerializedSettingsLength = 18
+
+// ProfileInteractive is a settings profile for interactive logins with a tight latency budget.
+//
+// The profile follows the OWASP Password Storage Cheat Sheet baseline recommendation for Argon2id.
+// It is the cheapest of the provided profiles and should be considered the minimum for password
+// storage. A single derivation takes roughly 25-50 ms on commodity hardware.
+//
+// The settings are as follows:
+//   - Memory: 19 MiB (19 * 1024 KiB)
+//   - Time: 2 iterations
+//   - Threads: 1 thread
+//   - SaltLength: 16 bytes for the salt
+//   - KeyLength: 32 bytes for the derived key
+var ProfileInteractive = Settings{
+	Memory:     19 * 1024,
+	Time:       2,
+	Threads:    1,
+	SaltLength: 16,
+	KeyLength:  32,
+	Variant:    VariantID,
+	Version:    argon2.Version,
+}
+
+// ProfileModerate is a settings profile that balances security and latency for most web applications.
+//
+// The profile follows the second recommended option of RFC 9106 for memory-constrained environments.
+// A single derivation takes roughly 75-150 ms on commodity hardware with 4 cores.
+//
+// The settings are as follows:
+//   - Memory: 64 MiB (64 * 1024 KiB)
+//   - Time: 3 iterations
+//   - Threads: 4 parallel threads
+//   - SaltLength: 16 bytes for the salt
+//   - KeyLength: 32 bytes for the derived key
+var ProfileModerate = Settings{
+	Memory:     64 * 1024,
+	Time:       3,
+	Threads:    4,
+	SaltLength: 16,
+	KeyLength:  32,
+	Variant:    VariantID,
+	Version:    argon2.Version,
+}
+
+// ProfileSensitive is a settings profile for highly sensitive secrets where latency is less important,
+// e.g. for administrative accounts or for deriving encryption keys.
+//
+// This profile is equal to DefaultSettings. A single derivation takes roughly 1 s on commodity hardware
+// with 4 cores and requires 1 GiB of memory per concurrent derivation.
 //
-// The default settings are as follows:
-//   - Memory: 1536 MB (1536 * 1024 KB)
+// The settings are as follows:
+//   - Memory: 1 GiB (1024 * 1024 KiB)
 //   - Time: 2 iterations
 //   - Threads: 4 parallel threads
 //   - SaltLength: 16 bytes for the salt
 //   - KeyLength: 32 bytes for the derived key
-var DefaultSettings = Settings{
+var ProfileSensitive = Settings{
 	Memory:     1024 * 1024,
 	Time:       2,
 	Threads:    4,
 	SaltLength: 16,
 	KeyLength:  32,
+	Variant:    VariantID,
+	Version:    argon2.Version,
 }
 
+// DefaultSettings is the default configuration for Argon2 hashing.
+//
+// This variable provides default values for the `Settings` struct that can be used
+// in hash derivation and validation when custom settings are not specified. The values
+// are chosen to provide a reasonable balance between security and performance, suitable
+// for most general use cases.
+//
+// For compatibility, DefaultSettings are equal to ProfileSensitive. The default settings
+// are as follows:
+//   - Memory: 1 GiB (1024 * 1024 KiB)
+//   - Time: 2 iterations
+//   - Threads: 4 parallel threads
+//   - SaltLength: 16 bytes for the salt
+//   - KeyLength: 32 bytes for the derived key
+//   - Variant: Argon2id
+//   - Version: 0x13 (19)
+var DefaultSettings = ProfileSensitive
+
 // NewSettings creates a new Settings struct with the specified parameters.
 //
 // This function initializes a Settings struct with the given memory, time, threads,
 // salt length, and key length values. It provides a convenient way to configure Argon2
-// key derivation settings.
+// key derivation settings. The returned Settings always use the Argon2id variant and the
+// current Argon2 version.
 //
 // Parameters:
 //   - mem: The amount of memory (in KB) to be used by the Argon2 algorithm.
@@ -81,7 +322,220 @@ func NewSettings(mem, time uint32, threads uint8, saltLen, keyLen uint32) Settin
 		Threads:    threads,
 		SaltLength: saltLen,
 		KeyLength:  keyLen,
+		Variant:    VariantID,
+		Version:    argon2.Version,
+	}
+}
+
+// NewSettingsMiB creates a new Settings struct like NewSettings, but takes the memory in
+// mebibytes instead of kibibytes.
+//
+// The Memory field of the Settings is specified in KiB, which is a common source of weak
+// configurations, e.g. when Memory is set to 128 with 128 MiB in mind. This constructor
+// converts the memory internally, so NewSettingsMiB(128, ...) results in a Memory of 128 MiB.
+//
+// Parameters:
+//   - memMiB: The amount of memory (in MiB) to be used by the Argon2 algorithm.
+//   - time: The number of iterations (or passes) for Argon2.
+//   - threads: The number of parallel threads used during hashing.
+//   - saltLen: The length of the salt in bytes.
+//   - keyLen: The length of the derived key in bytes.
+//
+// Returns:
+//   - A Settings struct initialized with the provided values.
+func NewSettingsMiB(memMiB, time uint32, threads uint8, saltLen, keyLen uint32) Settings {
+	return NewSettings(mibToKiB(memMiB), time, threads, saltLen, keyLen)
+}
+
+// WithDefaults returns a copy of the Settings in which every zero-valued cost or length field is
+// replaced by the corresponding field of DefaultSettings.
+//
+// This allows to construct partial Settings with a struct literal, e.g. to override only the
+// Memory, and fill in the remaining parameters from the defaults:
+//
+//	settings := argon2.Settings{Memory: 128 * 1024}.WithDefaults()
+//
+// The fields Memory, Time, Threads, SaltLength and KeyLength are filled in. Variant, Version,
+// Keyed and Timestamped are kept as they are, since their zero values are valid choices: a zero
+// Variant is VariantID and a zero Version is the current Argon2 version. Derive does not fill in
+// zero fields implicitly, so WithDefaults has to be called explicitly.
+//
+// Returns:
+//   - A copy of the Settings with the zero-valued fields replaced by the defaults.
+func (s Settings) WithDefaults() Settings {
+	if s.Memory == 0 {
+		s.Memory = DefaultSettings.Memory
+	}
+	if s.Time == 0 {
+		s.Time = DefaultSettings.Time
+	}
+	if s.Threads == 0 {
+		s.Threads = DefaultSettings.Threads
+	}
+	if s.SaltLength == 0 {
+		s.SaltLength = DefaultSettings.SaltLength
+	}
+	if s.KeyLength == 0 {
+		s.KeyLength = DefaultSettings.KeyLength
+	}
+	return s
+}
+
+// ParseSettings parses Settings from a comma-separated list of key=value pairs, e.g. from an
+// environment variable like `ARGON2=m=131072,t=3,p=4,keylen=32,saltlen=16`.
+//
+// Parsing starts from DefaultSettings, so only the parameters that differ from the defaults need
+// to be given. Keys are case-insensitive and surrounding whitespace is ignored. The following
+// keys are accepted:
+//   - m, memory: The memory cost in KiB.
+//   - t, time: The number of iterations.
+//   - p, parallelism, threads: The number of parallel threads.
+//   - saltlen: The salt length in bytes.
+//   - keylen: The key length in bytes.
+//   - variant: The name of the Argon2 variant, e.g. "argon2id".
+//
+// Parameters:
+//   - s: The comma-separated list of key=value pairs.
+//
+// Returns:
+//   - The parsed Settings.
+//   - An error if a pair is malformed, a key is unknown or given more than once, a value is not
+//     a valid number, or an *InvalidSettingError if the resulting Settings are outside the
+//     allowed ranges.
+func ParseSettings(s string) (Settings, error) {
+	settings := DefaultSettings
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if !found || key == "" || value == "" {
+			return Settings{}, fmt.Errorf("failed to parse settings: invalid parameter: %q", pair)
+		}
+		name, ok := settingsParamAliases[key]
+		if !ok {
+			return Settings{}, fmt.Errorf("failed to parse settings: unknown parameter: %q", key)
+		}
+		if seen[name] {
+			return Settings{}, fmt.Errorf("failed to parse settings: duplicate parameter: %q", key)
+		}
+		seen[name] = true
+		if err := settings.setParam(name, value); err != nil {
+			return Settings{}, fmt.Errorf("failed to parse settings: invalid %s parameter: %q", name, value)
+		}
+	}
+	if err := settings.Validate(); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// settingsParamAliases maps the keys that are accepted by ParseSettings to their canonical name.
+var settingsParamAliases = map[string]string{
+	"m":           "memory",
+	"memory":      "memory",
+	"t":           "time",
+	"time":        "time",
+	"p":           "parallelism",
+	"parallelism": "parallelism",
+	"threads":     "parallelism",
+	"saltlen":     "saltlen",
+	"keylen":      "keylen",
+	"variant":     "variant",
+}
+
+// setParam sets the field of the Settings that belongs to the given canonical parameter name
+// of ParseSettings to the given value.
+func (s *Settings) setParam(name, value string) error {
+	if name == "variant" {
+		variant, ok := parseVariant(strings.ToLower(value))
+		if !ok {
+			return fmt.Errorf("unknown variant: %q", value)
+		}
+		s.Variant = variant
+		return nil
+	}
+	bitSize := 32
+	if name == "parallelism" {
+		bitSize = 8
+	}
+	number, err := strconv.ParseUint(value, 10, bitSize)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "memory":
+		s.Memory = uint32(number)
+	case "time":
+		s.Time = uint32(number)
+	case "parallelism":
+		s.Threads = uint8(number)
+	case "saltlen":
+		s.SaltLength = uint32(number)
+	case "keylen":
+		s.KeyLength = uint32(number)
 	}
+	return nil
+}
+
+// MemoryMiB returns the memory cost of the Settings in mebibytes.
+func (s Settings) MemoryMiB() float64 {
+	return float64(s.Memory) / 1024
+}
+
+// HashLength returns the total length in bytes of an Argon2 hash that is derived with the Settings.
+//
+// The length consists of the serialized settings header, the salt, the derived key and, for
+// Timestamped settings, the creation timestamp. It can be
+// used to pre-allocate buffers or to size a fixed-length database column, e.g. `BINARY(n)`, for the
+// hashes that are stored with the Settings.
+//
+// Returns:
+//   - The length of the serialized Argon2 hash in bytes.
+func (s Settings) HashLength() int {
+	return SerializedSettingsLength + int(s.payloadLength())
+}
+
+// payloadLength returns the length in bytes of the data that follows the serialized settings
+// header, i.e. the salt, the derived key and the optional creation timestamp.
+func (s Settings) payloadLength() uint64 {
+	length := uint64(s.SaltLength) + uint64(s.KeyLength)
+	if s.Timestamped {
+		length += TimestampLength
+	}
+	return length
+}
+
+// EstimatedMemoryBytes returns the estimated peak memory in bytes that is used by the given
+// number of concurrent derivations or validations with the Settings.
+//
+// Each call of the Argon2 KDF allocates its own memory matrix of Memory KiB, which cannot be
+// reused between calls. The memory usage therefore grows linearly with the number of concurrent
+// calls: with DefaultSettings, 16 concurrent logins require 16 GiB of memory. To prevent a spike
+// of logins from exhausting the available memory, limit the number of concurrent calls, e.g. with
+// a semaphore in front of Derive and Validate or with the concurrency parameter of DeriveBatch,
+// and size the limit with this method. The estimate does not include the comparatively small
+// allocations for the salt, key and hash.
+//
+// Parameters:
+//   - concurrentCalls: The maximum number of concurrent derivations or validations. Values
+//     below 1 result in an estimate of zero.
+//
+// Returns:
+//   - The estimated peak memory in bytes.
+func (s Settings) EstimatedMemoryBytes(concurrentCalls int) uint64 {
+	if concurrentCalls < 1 {
+		return 0
+	}
+	return uint64(s.Memory) * 1024 * uint64(concurrentCalls)
+}
+
+// mibToKiB converts the given amount of memory from MiB to KiB. Values that do not fit into
+// a uint32 are capped at math.MaxUint32 instead of overflowing.
+func mibToKiB(mib uint32) uint32 {
+	if mib > math.MaxUint32/1024 {
+		return math.MaxUint32
+	}
+	return mib * 1024
 }
 
 // Serialize converts the Settings struct into a byte slice.
@@ -92,9 +546,11 @@ func NewSettings(mem, time uint32, threads uint8, saltLen, keyLen uint32) Settin
 // the following fields in this order:
 //   - Memory (4 bytes)
 //   - Time (4 bytes)
-//   - Threads (2 bytes, converted to uint16)
+//   - Threads (1 byte)
+//   - Variant (1 byte, the highest bit is set for keyed hashes)
 //   - SaltLength (4 bytes)
 //   - KeyLength (4 bytes)
+//   - Version (1 byte, a zero Version is serialized as the current Argon2 version)
 //
 // The total size of the resulting byte slice is determined by the constant `SerializedSettingsLength`.
 //
@@ -102,38 +558,394 @@ func NewSettings(mem, time uint32, threads uint8, saltLen, keyLen uint32) Settin
 //   - A byte slice containing the serialized Settings struct in little-endian byte order.
 func (s Settings) Serialize() []byte {
 	buffer := make([]byte, SerializedSettingsLength)
-	binary.LittleEndian.PutUint32(buffer[0:4], s.Memory)
-	binary.LittleEndian.PutUint32(buffer[4:8], s.Time)
-	binary.LittleEndian.PutUint16(buffer[8:10], uint16(s.Threads))
-	binary.LittleEndian.PutUint32(buffer[10:14], s.SaltLength)
-	binary.LittleEndian.PutUint32(buffer[14:18], s.KeyLength)
+	s.serializeTo(buffer)
+	return buffer
+}
+
+// serializeTo implements the serialization of Serialize by writing the serialized Settings into
+// the given buffer. The caller must ensure that p is at least SerializedSettingsLength bytes long.
+func (s Settings) serializeTo(p []byte) {
+	p[0] = SerializedFormatVersion
+	s.serializeUnversionedTo(p[1:], binary.LittleEndian)
+}
+
+// SerializeBigEndian converts the Settings struct into a byte slice using big-endian encoding.
+//
+// The layout is identical to the layout of Serialize, including the leading format-version
+// byte, but all multi-byte fields are encoded in big-endian byte order. It is meant for archives
+// on systems that expect big-endian records. The serialized settings do not contain a flag for
+// the byte order, so they can only be deserialized with SettingsFromBytesBigEndian. Hashes of
+// this package always use little-endian encoding.
+//
+// Returns:
+//   - A byte slice containing the serialized Settings struct in big-endian byte order.
+func (s Settings) SerializeBigEndian() []byte {
+	buffer := make([]byte, SerializedSettingsLength)
+	buffer[0] = SerializedFormatVersion
+	s.serializeUnversionedTo(buffer[1:], binary.BigEndian)
 	return buffer
 }
 
+// serializeUnversionedTo writes the Settings in the format version 0 layout, without the leading
+// format-version byte, using the given byte order. The caller must ensure that p is at least
+// UnversionedSerializedSettingsLength bytes long.
+func (s Settings) serializeUnversionedTo(p []byte, order binary.ByteOrder) {
+	order.PutUint32(p[0:4], s.Memory)
+	order.PutUint32(p[4:8], s.Time)
+	p[8] = s.Threads
+	p[9] = byte(s.Variant)
+	if s.Keyed {
+		p[9] |= keyedFlag
+	}
+	if s.Timestamped {
+		p[9] |= timestampedFlag
+	}
+	order.PutUint32(p[10:14], s.SaltLength)
+	order.PutUint32(p[14:18], s.KeyLength)
+	p[18] = s.version()
+}
+
 // SettingsFromBytes deserializes a byte slice into a Settings struct.
 //
 // This function takes a byte slice representing serialized `Settings` data and
 // converts it back into a `Settings` struct. The byte slice must contain the serialized
 // data in little-endian byte order, with the following field sizes and order:
+//   - Format version (1 byte)
 //   - Memory (4 bytes)
 //   - Time (4 bytes)
-//   - Threads (2 bytes, converted from uint16 to uint8)
+//   - Threads (1 byte)
+//   - Variant (1 byte, the highest bit is set for keyed hashes)
 //   - SaltLength (4 bytes)
 //   - KeyLength (4 bytes)
+//   - Version (1 byte)
+//
+// The function switches on the leading format-version byte. If the byte slice is at least
+// SerializedSettingsLength bytes long and starts with SerializedFormatVersion, it is read in the
+// current layout. Otherwise, it is read as format version 0 settings without the format-version
+// byte. If the byte slice is only LegacySerializedSettingsLength bytes long, it is treated as legacy
+// serialized settings without the version byte and the Version is set to the current Argon2 version.
+// Note that format version 0 settings with a Memory that ends in the byte 0x01 cannot be told apart
+// from current settings if additional data follows them, so pass exactly
+// UnversionedSerializedSettingsLength bytes for those.
+//
+// Threads is read from a single byte, so its valid range is 0-255 and no truncation takes place.
+// Early versions of the format stored Threads as a 2 byte uint16, but since Threads has always
+// been a uint8, the high byte was always zero and now holds the Variant. A hand-crafted value
+// above 255 in those two bytes is therefore not truncated to a different parallelism but shows
+// up as a different Variant. Such settings either fail Validate with an unknown variant or
+// derive a different key, so the validation of the affected hash fails instead of silently
+// succeeding with unintended parameters.
 //
 // The function returns a `Settings` struct with the values extracted from the byte slice.
+// It is safe to call with arbitrary input: if the byte slice is shorter than
+// LegacySerializedSettingsLength, an error is returned instead of panicking.
 //
 // Parameters:
 //   - p: A byte slice containing the serialized Settings data in little-endian byte order.
 //
 // Returns:
 //   - A Settings struct populated with the values extracted from the byte slice.
-func SettingsFromBytes(p []byte) Settings {
-	return Settings{
-		Memory:     binary.LittleEndian.Uint32(p[0:4]),
-		Time:       binary.LittleEndian.Uint32(p[4:8]),
-		Threads:    uint8(binary.LittleEndian.Uint16(p[8:10])),
-		SaltLength: binary.LittleEndian.Uint32(p[10:14]),
-		KeyLength:  binary.LittleEndian.Uint32(p[14:18]),
+//   - An error if the byte slice is too short to contain serialized Settings.
+func SettingsFromBytes(p []byte) (Settings, error) {
+	if len(p) < LegacySerializedSettingsLength {
+		return Settings{}, fmt.Errorf("invalid serialized settings length, got: %d, expected at least: %d",
+			len(p), LegacySerializedSettingsLength)
 	}
+	return settingsFromHeader(p), nil
+}
+
+// SettingsFromBytesBigEndian deserializes a byte slice that was created by SerializeBigEndian
+// into a Settings struct.
+//
+// Unlike SettingsFromBytes, only the current layout with the leading format-version byte is
+// supported, since the big-endian encoding was introduced after the format-version byte.
+//
+// Parameters:
+//   - p: A byte slice containing the serialized Settings data in big-endian byte order.
+//
+// Returns:
+//   - A Settings struct populated with the values extracted from the byte slice.
+//   - An error if the byte slice is too short or does not start with SerializedFormatVersion.
+func SettingsFromBytesBigEndian(p []byte) (Settings, error) {
+	if len(p) < SerializedSettingsLength {
+		return Settings{}, fmt.Errorf("invalid serialized settings length, got: %d, expected at least: %d",
+			len(p), SerializedSettingsLength)
+	}
+	if p[0] != SerializedFormatVersion {
+		return Settings{}, fmt.Errorf("unsupported serialized settings format version: %d", p[0])
+	}
+	return settingsFromFields(p[1:SerializedSettingsLength], binary.BigEndian), nil
+}
+
+// settingsFromHeader implements the deserialization of SettingsFromBytes without checking the
+// length of the byte slice. It switches on the format-version byte and reads the settings in the
+// current layout or in the format version 0 layout. The caller must ensure that p is at least
+// LegacySerializedSettingsLength bytes long.
+func settingsFromHeader(p []byte) Settings {
+	if len(p) >= SerializedSettingsLength && p[0] == SerializedFormatVersion {
+		return settingsFromBytes(p[1:SerializedSettingsLength])
+	}
+	return settingsFromBytes(p)
+}
+
+// settingsFromBytes deserializes Settings in the format version 0 layout, without the leading
+// format-version byte. The caller must ensure that p is at least LegacySerializedSettingsLength
+// bytes long.
+func settingsFromBytes(p []byte) Settings {
+	return settingsFromFields(p, binary.LittleEndian)
+}
+
+// settingsFromFields deserializes Settings in the format version 0 layout using the given byte
+// order. The caller must ensure that p is at least LegacySerializedSettingsLength bytes long.
+func settingsFromFields(p []byte, order binary.ByteOrder) Settings {
+	settings := Settings{
+		Memory:      order.Uint32(p[0:4]),
+		Time:        order.Uint32(p[4:8]),
+		Threads:     p[8],
+		SaltLength:  order.Uint32(p[10:14]),
+		KeyLength:   order.Uint32(p[14:18]),
+		Variant:     Variant(p[9] &^ flagMask),
+		Version:     argon2.Version,
+		Keyed:       p[9]&keyedFlag != 0,
+		Timestamped: p[9]&timestampedFlag != 0,
+	}
+	if len(p) >= UnversionedSerializedSettingsLength {
+		settings.Version = p[18]
+	}
+	return settings
+}
+
+// Validate checks whether the Settings are within the ranges that are required for a safe and
+// working Argon2 hash generation.
+//
+// The following bounds are enforced:
+//   - Threads: at least MinThreads (1)
+//   - Time: at least MinTime (1) and at most MaxTime (1024)
+//   - Memory: at least MinMemoryPerThread (8) KiB per thread and at most MaxMemory (4 GiB)
+//   - SaltLength: at least MinSaltLength (8) and at most MaxSaltLength (1024) bytes
+//   - KeyLength: at least MinKeyLength (4) and at most MaxKeyLength (1024) bytes
+//   - Variant: must be VariantID or VariantI, since Argon2d is not implemented by
+//     golang.org/x/crypto/argon2
+//
+// Salts and keys below the recommended lengths are accepted for backward compatibility. Use
+// ValidateWithStrictness to report or reject them.
+//
+// Returns:
+//   - nil if the Settings are valid.
+//   - An *InvalidSettingError identifying the offending field otherwise. For an unsupported
+//     Variant, the error also wraps ErrUnsupportedVariant.
+func (s Settings) Validate() error {
+	switch {
+	case s.Threads < MinThreads:
+		return &InvalidSettingError{Field: "Threads", Value: uint64(s.Threads),
+			Reason: fmt.Sprintf("must be at least %d", MinThreads)}
+	case s.Time < MinTime:
+		return &InvalidSettingError{Field: "Time", Value: uint64(s.Time),
+			Reason: fmt.Sprintf("must be at least %d", MinTime)}
+	case uint64(s.Memory) < MinMemoryPerThread*uint64(s.Threads):
+		return &InvalidSettingError{Field: "Memory", Value: uint64(s.Memory),
+			Reason: fmt.Sprintf("must be at least %d KiB per thread", MinMemoryPerThread)}
+	case s.SaltLength < MinSaltLength:
+		return &InvalidSettingError{Field: "SaltLength", Value: uint64(s.SaltLength),
+			Reason: fmt.Sprintf("must be at least %d bytes", MinSaltLength)}
+	case s.KeyLength < MinKeyLength:
+		return &InvalidSettingError{Field: "KeyLength", Value: uint64(s.KeyLength),
+			Reason: fmt.Sprintf("must be at least %d bytes", MinKeyLength)}
+	}
+	if err := s.validateCost(); err != nil {
+		return err
+	}
+	if err := s.validateVariant(); err != nil {
+		return err
+	}
+	return s.validateLengths()
+}
+
+// validateHeader checks the settings header of a stored hash with validateLengths, validateCost
+// and validateVariant. It is used by Scan, ReadFrom, UnmarshalBinary and DecodeHashes, so that all
+// decoding paths reject the same crafted or unsupported hashes. Unlike Validate, it does not check
+// the lower bounds, so that hashes with weak parameters can still be read and rehashed.
+func (s Settings) validateHeader() error {
+	if err := s.validateLengths(); err != nil {
+		return err
+	}
+	if err := s.validateCost(); err != nil {
+		return err
+	}
+	return s.validateVariant()
+}
+
+// validateCost checks the Memory and Time of the Settings against MaxMemory and MaxTime. It is
+// used by Validate and validateHeader, so that stored hashes with cost parameters outside the
+// format limits are rejected before the Argon2 KDF is executed with them.
+func (s Settings) validateCost() error {
+	switch {
+	case s.Memory > MaxMemory:
+		return &InvalidSettingError{Field: "Memory", Value: uint64(s.Memory),
+			Reason: fmt.Sprintf("must be at most %d KiB", MaxMemory)}
+	case s.Time > MaxTime:
+		return &InvalidSettingError{Field: "Time", Value: uint64(s.Time),
+			Reason: fmt.Sprintf("must be at most %d", MaxTime)}
+	}
+	return nil
+}
+
+// validateVariant checks whether the Variant of the Settings is supported. It is used by Validate
+// and validateHeader, so that stored hashes with an unsupported variant are rejected before they
+// fail to validate any password.
+func (s Settings) validateVariant() error {
+	if s.Variant.supported() {
+		return nil
+	}
+	reason := "unknown Argon2 variant"
+	if s.Variant == VariantD {
+		reason = "the Argon2d variant is not supported by golang.org/x/crypto/argon2"
+	}
+	return fmt.Errorf("%w: %w", ErrUnsupportedVariant,
+		&InvalidSettingError{Field: "Variant", Value: uint64(s.Variant), Reason: reason})
+}
+
+// validateLengths checks the SaltLength and KeyLength of the Settings against MaxSaltLength and
+// MaxKeyLength. It is used by Validate and validateHeader.
+func (s Settings) validateLengths() error {
+	switch {
+	case s.SaltLength > MaxSaltLength:
+		return &InvalidSettingError{Field: "SaltLength", Value: uint64(s.SaltLength),
+			Reason: fmt.Sprintf("must be at most %d bytes", MaxSaltLength)}
+	case s.KeyLength > MaxKeyLength:
+		return &InvalidSettingError{Field: "KeyLength", Value: uint64(s.KeyLength),
+			Reason: fmt.Sprintf("must be at most %d bytes", MaxKeyLength)}
+	}
+	return nil
+}
+
+// ValidateWithStrictness checks whether the Settings are valid like Validate and additionally
+// checks the SaltLength and KeyLength against RecommendedSaltLength and RecommendedKeyLength.
+//
+// Short salts and keys weaken the hash, but are accepted by Validate for backward compatibility
+// with existing hashes. ValidateWithStrictness reports them, so that weak, e.g. copy-pasted,
+// configurations can be surfaced: in LenientSettings mode they are returned as warnings, in
+// StrictSettings mode they are rejected.
+//
+// Parameters:
+//   - strictness: Whether short salts and keys are reported as warnings or rejected.
+//
+// Returns:
+//   - An *InvalidSettingError for every length that is below the recommended length.
+//   - The error of Validate, if the Settings are invalid. In StrictSettings mode, the first
+//     warning if any length is below the recommended length.
+func (s Settings) ValidateWithStrictness(strictness Strictness) ([]*InvalidSettingError, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	warnings := s.lengthWarnings()
+	if strictness == StrictSettings && len(warnings) > 0 {
+		return warnings, warnings[0]
+	}
+	return warnings, nil
+}
+
+// lengthWarnings returns an *InvalidSettingError for each of the SaltLength and KeyLength of the
+// Settings that is below the recommended length.
+func (s Settings) lengthWarnings() []*InvalidSettingError {
+	var warnings []*InvalidSettingError
+	if s.SaltLength < RecommendedSaltLength {
+		warnings = append(warnings, &InvalidSettingError{Field: "SaltLength", Value: uint64(s.SaltLength),
+			Reason: fmt.Sprintf("should be at least %d bytes", RecommendedSaltLength)})
+	}
+	if s.KeyLength < RecommendedKeyLength {
+		warnings = append(warnings, &InvalidSettingError{Field: "KeyLength", Value: uint64(s.KeyLength),
+			Reason: fmt.Sprintf("should be at least %d bytes", RecommendedKeyLength)})
+	}
+	return warnings
+}
+
+// Equal reports whether the Settings and the other Settings describe the same Argon2 parameters.
+//
+// All fields are compared. A zero Version is treated as the current Argon2 version, so Settings
+// created without an explicit version compare equal to the Settings deserialized from a hash.
+//
+// Parameters:
+//   - other: The Settings to compare against.
+//
+// Returns:
+//   - true if all parameters are equal, false otherwise.
+func (s Settings) Equal(other Settings) bool {
+	return s.Memory == other.Memory &&
+		s.Time == other.Time &&
+		s.Threads == other.Threads &&
+		s.SaltLength == other.SaltLength &&
+		s.KeyLength == other.KeyLength &&
+		s.Variant == other.Variant &&
+		s.version() == other.version() &&
+		s.Keyed == other.Keyed &&
+		s.Timestamped == other.Timestamped
+}
+
+// version returns the Argon2 version of the Settings. A zero Version is treated as the current
+// Argon2 version implemented by golang.org/x/crypto/argon2.
+func (s Settings) version() uint8 {
+	if s.Version == 0 {
+		return argon2.Version
+	}
+	return s.Version
+}
+
+// StepToward returns the next Settings on an incremental path from the Settings to the target.
+//
+// Raising the parameters of all stored hashes at once causes every user to be rehashed with the
+// full new cost at their next login. StepToward allows to roll out stronger parameters gradually
+// instead: a login handler that finds NeedsRehash(target) to be true rehashes with the next step
+// only, so the cost grows by at most one step per login. Since the next step is computed from the
+// stored settings alone, it does not require any state to be kept across restarts.
+//
+// The Memory, Time and Threads are increased by the corresponding field of step, but never beyond
+// the target. A zero field in step moves that parameter to the target right away. Parameters that
+// already exceed the target are kept and never lowered. The Memory is raised to at least
+// MinMemoryPerThread KiB per thread if needed. The SaltLength and KeyLength are set to the larger
+// of the current and the target value, and the Variant and Version are taken from the target.
+// The Keyed and Timestamped flags are cleared, since they are set by the package.
+//
+// Parameters:
+//   - target: The Settings that should eventually be reached.
+//   - step: The maximum increase of the Memory, Time and Threads per step.
+//
+// Returns:
+//   - The Settings of the next step. If the Settings already comply with the target, the result
+//     complies with it as well, so that NeedsRehash(target) is false for it.
+func (s Settings) StepToward(target, step Settings) Settings {
+	next := Settings{
+		Memory:     stepUint32(s.Memory, target.Memory, step.Memory),
+		Time:       stepUint32(s.Time, target.Time, step.Time),
+		Threads:    uint8(stepUint32(uint32(s.Threads), uint32(target.Threads), uint32(step.Threads))),
+		SaltLength: max(s.SaltLength, target.SaltLength),
+		KeyLength:  max(s.KeyLength, target.KeyLength),
+		Variant:    target.Variant,
+		Version:    target.Version,
+	}
+	next.Memory = uint32(max(uint64(next.Memory), MinMemoryPerThread*uint64(next.Threads)))
+	return next
+}
+
+// stepUint32 increases current by step without exceeding target. A zero step reaches the target
+// right away. If current already exceeds the target, it is returned unchanged.
+func stepUint32(current, target, step uint32) uint32 {
+	if current >= target {
+		return current
+	}
+	if step == 0 || uint64(current)+uint64(step) >= uint64(target) {
+		return target
+	}
+	return current + step
+}
+
+// weakerThan reports whether any of the cost parameters of the Settings is lower than the
+// corresponding parameter of the target settings, or whether the Variant differs.
+func (s Settings) weakerThan(target Settings) bool {
+	return s.Memory < target.Memory ||
+		s.Time < target.Time ||
+		s.Threads < target.Threads ||
+		s.SaltLength < target.SaltLength ||
+		s.KeyLength < target.KeyLength ||
+		s.Variant != target.Variant
 }
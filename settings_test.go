@@ -6,9 +6,40 @@ package argon2
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
 	"testing"
+	"testing/quick"
 )
 
+func TestProfiles(t *testing.T) {
+	t.Run("default settings equal the sensitive profile", func(t *testing.T) {
+		if DefaultSettings != ProfileSensitive {
+			t.Errorf("default settings are not as expected, got: %+v, want: %+v", DefaultSettings,
+				ProfileSensitive)
+		}
+	})
+	t.Run("profiles are valid", func(t *testing.T) {
+		for name, profile := range map[string]Settings{
+			"interactive": ProfileInteractive,
+			"moderate":    ProfileModerate,
+			"sensitive":   ProfileSensitive,
+		} {
+			if err := profile.Validate(); err != nil {
+				t.Errorf("%s profile should be valid, got: %s", name, err)
+			}
+		}
+	})
+	t.Run("profiles are ordered by cost", func(t *testing.T) {
+		if ProfileInteractive.Memory >= ProfileModerate.Memory || ProfileModerate.Memory >= ProfileSensitive.Memory {
+			t.Error("profiles are not ordered by memory cost")
+		}
+	})
+}
+
 func TestNewSettings(t *testing.T) {
 	t.Run("new settings with default settings", func(t *testing.T) {
 		settings := NewSettings(DefaultSettings.Memory, DefaultSettings.Time, DefaultSettings.Threads,
@@ -60,6 +91,216 @@ func TestNewSettings(t *testing.T) {
 	})
 }
 
+func TestNewSettingsMiB(t *testing.T) {
+	t.Run("memory is converted to KiB", func(t *testing.T) {
+		settings := NewSettingsMiB(128, 3, 4, 16, 32)
+		if settings.Memory != 128*1024 {
+			t.Errorf("memory is not as expected, got: %d, want: %d", settings.Memory, 128*1024)
+		}
+		if !settings.Equal(NewSettings(128*1024, 3, 4, 16, 32)) {
+			t.Errorf("settings are not as expected, got: %+v", settings)
+		}
+	})
+	t.Run("memory overflow is capped", func(t *testing.T) {
+		settings := NewSettingsMiB(math.MaxUint32, 3, 4, 16, 32)
+		if settings.Memory != math.MaxUint32 {
+			t.Errorf("memory is not as expected, got: %d, want: %d", settings.Memory, uint32(math.MaxUint32))
+		}
+	})
+}
+
+func TestSettings_WithDefaults(t *testing.T) {
+	t.Run("zero settings result in the defaults", func(t *testing.T) {
+		if got := (Settings{}).WithDefaults(); !got.Equal(DefaultSettings) {
+			t.Errorf("zero settings with defaults are not as expected, got: %+v, want: %+v", got, DefaultSettings)
+		}
+	})
+	t.Run("partial settings keep the given fields", func(t *testing.T) {
+		got := Settings{Memory: 128 * 1024, KeyLength: 64, Variant: VariantI}.WithDefaults()
+		want := DefaultSettings
+		want.Memory, want.KeyLength, want.Variant = 128*1024, 64, VariantI
+		if !got.Equal(want) {
+			t.Errorf("partial settings with defaults are not as expected, got: %+v, want: %+v", got, want)
+		}
+	})
+	t.Run("complete settings are unchanged", func(t *testing.T) {
+		if got := testFastSettings.WithDefaults(); got != testFastSettings {
+			t.Errorf("complete settings should be unchanged, got: %+v, want: %+v", got, testFastSettings)
+		}
+	})
+	t.Run("defaults are read at call time", func(t *testing.T) {
+		defaults := DefaultSettings
+		t.Cleanup(func() { DefaultSettings = defaults })
+		DefaultSettings = ProfileInteractive
+		if got := (Settings{}).WithDefaults(); !got.Equal(ProfileInteractive) {
+			t.Errorf("zero settings should use the current defaults, got: %+v, want: %+v", got,
+				ProfileInteractive)
+		}
+	})
+}
+
+func TestParseSettings(t *testing.T) {
+	t.Run("parse with short keys", func(t *testing.T) {
+		settings, err := ParseSettings("m=131072,t=3,p=4,keylen=32,saltlen=16")
+		if err != nil {
+			t.Fatalf("failed to parse settings: %s", err)
+		}
+		want := NewSettings(131072, 3, 4, 16, 32)
+		if !settings.Equal(want) {
+			t.Errorf("parsed settings are not as expected, got: %+v, want: %+v", settings, want)
+		}
+	})
+	t.Run("parse with long keys and whitespace", func(t *testing.T) {
+		settings, err := ParseSettings(" Memory = 65536, time=2 ,parallelism=2, variant=argon2i ")
+		if err != nil {
+			t.Fatalf("failed to parse settings: %s", err)
+		}
+		want := DefaultSettings
+		want.Memory, want.Time, want.Threads, want.Variant = 65536, 2, 2, VariantI
+		if !settings.Equal(want) {
+			t.Errorf("parsed settings are not as expected, got: %+v, want: %+v", settings, want)
+		}
+	})
+	t.Run("parse with threads alias", func(t *testing.T) {
+		settings, err := ParseSettings("threads=8")
+		if err != nil {
+			t.Fatalf("failed to parse settings: %s", err)
+		}
+		if settings.Threads != 8 {
+			t.Errorf("threads are not as expected, got: %d, want: %d", settings.Threads, 8)
+		}
+	})
+	t.Run("parse keeps defaults for missing keys", func(t *testing.T) {
+		settings, err := ParseSettings("t=5")
+		if err != nil {
+			t.Fatalf("failed to parse settings: %s", err)
+		}
+		want := DefaultSettings
+		want.Time = 5
+		if !settings.Equal(want) {
+			t.Errorf("parsed settings are not as expected, got: %+v, want: %+v", settings, want)
+		}
+	})
+	t.Run("parse with invalid input fails", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input string
+		}{
+			{"empty string", ""},
+			{"missing value", "m="},
+			{"missing separator", "m131072"},
+			{"unknown key", "m=131072,x=1"},
+			{"duplicate key", "m=131072,memory=65536"},
+			{"non-numeric value", "t=three"},
+			{"negative value", "t=-1"},
+			{"memory overflow", "m=4294967296"},
+			{"threads overflow", "p=256"},
+			{"unknown variant", "variant=argon2x"},
+			{"trailing comma", "m=131072,"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := ParseSettings(tt.input); err == nil {
+					t.Errorf("parsing %q should have failed", tt.input)
+				}
+			})
+		}
+	})
+	t.Run("parse with out-of-range value fails", func(t *testing.T) {
+		_, err := ParseSettings("saltlen=4")
+		var settingErr *InvalidSettingError
+		if !errors.As(err, &settingErr) {
+			t.Fatalf("parsing should have failed with an InvalidSettingError, got: %v", err)
+		}
+		if settingErr.Field != "SaltLength" {
+			t.Errorf("invalid field is not as expected, got: %s, want: %s", settingErr.Field, "SaltLength")
+		}
+	})
+}
+
+func TestSettings_MemoryMiB(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		want     float64
+	}{
+		{"default settings", DefaultSettings, 1024},
+		{"interactive profile", ProfileInteractive, 19},
+		{"fractional memory", NewSettings(512, 1, 1, 16, 32), 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.MemoryMiB(); got != tt.want {
+				t.Errorf("memory in MiB is not as expected, got: %f, want: %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettings_HashLength(t *testing.T) {
+	t.Run("hash length matches derived hash", func(t *testing.T) {
+		derived, err := Derive(testPassPhrase, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if got := testFastSettings.HashLength(); got != len(derived) {
+			t.Errorf("hash length is not as expected, got: %d, want: %d", got, len(derived))
+		}
+	})
+	t.Run("hash length of default settings", func(t *testing.T) {
+		if got := DefaultSettings.HashLength(); got != SerializedSettingsLength+48 {
+			t.Errorf("hash length is not as expected, got: %d, want: %d", got, SerializedSettingsLength+48)
+		}
+	})
+}
+
+func TestSettings_EstimatedMemoryBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		calls    int
+		want     uint64
+	}{
+		{"single call", ProfileModerate, 1, 64 << 20},
+		{"concurrent calls", DefaultSettings, 16, 16 << 30},
+		{"maximum memory", NewSettings(math.MaxUint32, 1, 1, 16, 32), 2, math.MaxUint32 * 1024 * 2},
+		{"zero calls", DefaultSettings, 0, 0},
+		{"negative calls", DefaultSettings, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.EstimatedMemoryBytes(tt.calls); got != tt.want {
+				t.Errorf("estimated memory is not as expected, got: %d, want: %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializedSettingsLength(t *testing.T) {
+	t.Run("serialized default settings match the constant", func(t *testing.T) {
+		if got := len(DefaultSettings.Serialize()); got != SerializedSettingsLength {
+			t.Errorf("serialized settings length is not as expected, got: %d, want: %d", got,
+				SerializedSettingsLength)
+		}
+	})
+	t.Run("serialized zero settings match the constant", func(t *testing.T) {
+		if got := len(Settings{}.Serialize()); got != SerializedSettingsLength {
+			t.Errorf("serialized settings length is not as expected, got: %d, want: %d", got,
+				SerializedSettingsLength)
+		}
+	})
+	t.Run("older formats are shorter by their missing bytes", func(t *testing.T) {
+		if UnversionedSerializedSettingsLength != SerializedSettingsLength-1 {
+			t.Errorf("unversioned settings length is not as expected, got: %d, want: %d",
+				UnversionedSerializedSettingsLength, SerializedSettingsLength-1)
+		}
+		if LegacySerializedSettingsLength != UnversionedSerializedSettingsLength-1 {
+			t.Errorf("legacy settings length is not as expected, got: %d, want: %d",
+				LegacySerializedSettingsLength, UnversionedSerializedSettingsLength-1)
+		}
+	})
+}
+
 func TestSettings_Serialize(t *testing.T) {
 	t.Run("serializing default settings", func(t *testing.T) {
 		serialized := DefaultSettings.Serialize()
@@ -67,8 +308,8 @@ func TestSettings_Serialize(t *testing.T) {
 			t.Fatal("serialized settings is not the correct length")
 		}
 		want := []byte{
-			0x00, 0x00, 0x10, 0x00, 0x02, 0x00, 0x00, 0x00, 0x04, 0x00, 0x10, 0x00, 0x00,
-			0x00, 0x20, 0x00, 0x00, 0x00,
+			0x01, 0x00, 0x00, 0x10, 0x00, 0x02, 0x00, 0x00, 0x00, 0x04, 0x00, 0x10, 0x00, 0x00,
+			0x00, 0x20, 0x00, 0x00, 0x00, 0x13,
 		}
 		if !bytes.Equal(serialized, want) {
 			t.Errorf("serialized settings is not as expected: got %x, want %x", serialized, want)
@@ -79,11 +320,40 @@ func TestSettings_Serialize(t *testing.T) {
 		if len(serialized) != SerializedSettingsLength {
 			t.Fatal("serialized settings is not the correct length")
 		}
-		want := testDerived[:SerializedSettingsLength]
+		want := append([]byte{SerializedFormatVersion}, testDerived[:LegacySerializedSettingsLength]...)
+		want = append(want, 0x13)
 		if !bytes.Equal(serialized, want) {
 			t.Errorf("serialized settings is not as expected: got %x, want %x", serialized, want)
 		}
 	})
+	t.Run("serializing settings with zero version uses current version", func(t *testing.T) {
+		settings := testSettings
+		settings.Version = 0
+		serialized := settings.Serialize()
+		if serialized[SerializedSettingsLength-1] != 0x13 {
+			t.Errorf("serialized version is not as expected: got %d, want %d",
+				serialized[SerializedSettingsLength-1], 0x13)
+		}
+	})
+	t.Run("serializing settings with Argon2i variant", func(t *testing.T) {
+		settings := testSettings
+		settings.Variant = VariantI
+		serialized := settings.Serialize()
+		if serialized[10] != byte(VariantI) {
+			t.Errorf("serialized variant is not as expected: got %d, want %d", serialized[10], VariantI)
+		}
+		deserialized, err := SettingsFromBytes(serialized)
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if deserialized.Variant != VariantI {
+			t.Errorf("deserialized variant is not as expected: got %d, want %d", deserialized.Variant, VariantI)
+		}
+		if deserialized.Threads != settings.Threads {
+			t.Errorf("deserialized threads are not as expected: got %d, want %d", deserialized.Threads,
+				settings.Threads)
+		}
+	})
 	t.Run("serializing custom settings", func(t *testing.T) {
 		settings := Settings{
 			Memory:     123,
@@ -97,8 +367,8 @@ func TestSettings_Serialize(t *testing.T) {
 			t.Fatal("serialized settings is not the correct length")
 		}
 		want := []byte{
-			0x7b, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x08, 0x00, 0x7b, 0x00, 0x00, 0x00,
-			0x41, 0x01, 0x00, 0x00,
+			0x01, 0x7b, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x08, 0x00, 0x7b, 0x00, 0x00, 0x00,
+			0x41, 0x01, 0x00, 0x00, 0x13,
 		}
 		if !bytes.Equal(serialized, want) {
 			t.Fatalf("serialized settings is not as expected: got %x, want %x", serialized, want)
@@ -106,11 +376,60 @@ func TestSettings_Serialize(t *testing.T) {
 	})
 }
 
+func TestSettings_SerializeBigEndian(t *testing.T) {
+	settings := Settings{
+		Memory:     0x01020304,
+		Time:       0x05060708,
+		Threads:    0x09,
+		SaltLength: 0x0a0b0c0d,
+		KeyLength:  0x0e0f1011,
+		Variant:    VariantI,
+	}
+	t.Run("little-endian layout is pinned", func(t *testing.T) {
+		want := []byte{
+			0x01, 0x04, 0x03, 0x02, 0x01, 0x08, 0x07, 0x06, 0x05, 0x09, 0x01, 0x0d, 0x0c, 0x0b, 0x0a,
+			0x11, 0x10, 0x0f, 0x0e, 0x13,
+		}
+		if got := settings.Serialize(); !bytes.Equal(got, want) {
+			t.Errorf("serialized settings are not as expected, got: %x, want: %x", got, want)
+		}
+	})
+	t.Run("big-endian layout is pinned", func(t *testing.T) {
+		want := []byte{
+			0x01, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x01, 0x0a, 0x0b, 0x0c, 0x0d,
+			0x0e, 0x0f, 0x10, 0x11, 0x13,
+		}
+		if got := settings.SerializeBigEndian(); !bytes.Equal(got, want) {
+			t.Errorf("serialized settings are not as expected, got: %x, want: %x", got, want)
+		}
+	})
+	t.Run("big-endian round trip", func(t *testing.T) {
+		deserialized, err := SettingsFromBytesBigEndian(settings.SerializeBigEndian())
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if !deserialized.Equal(settings) {
+			t.Errorf("deserialized settings are not as expected, got: %+v, want: %+v", deserialized, settings)
+		}
+	})
+	t.Run("big-endian deserialization of invalid data fails", func(t *testing.T) {
+		if _, err := SettingsFromBytesBigEndian(settings.SerializeBigEndian()[1:]); err == nil {
+			t.Error("deserializing too short settings should fail")
+		}
+		if _, err := SettingsFromBytesBigEndian(make([]byte, SerializedSettingsLength)); err == nil {
+			t.Error("deserializing settings with unknown format version should fail")
+		}
+	})
+}
+
 func TestSettingsFromBytes(t *testing.T) {
 	t.Run("deserializing default settings", func(t *testing.T) {
 		settings := DefaultSettings
 		serialized := settings.Serialize()
-		deserialized := SettingsFromBytes(serialized)
+		deserialized, err := SettingsFromBytes(serialized)
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
 		if settings.Memory != deserialized.Memory {
 			t.Errorf("deserialized settings for memory is not as expected: got %d, want %d", deserialized.Memory,
 				settings.Memory)
@@ -132,10 +451,70 @@ func TestSettingsFromBytes(t *testing.T) {
 				deserialized.KeyLength, settings.KeyLength)
 		}
 	})
+	t.Run("deserializing legacy settings defaults to Argon2id", func(t *testing.T) {
+		deserialized, err := SettingsFromBytes(testDerived[:LegacySerializedSettingsLength])
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if deserialized.Variant != VariantID {
+			t.Errorf("deserialized variant is not as expected: got %d, want %d", deserialized.Variant, VariantID)
+		}
+		if deserialized.Version != 0x13 {
+			t.Errorf("deserialized version is not as expected: got %d, want %d", deserialized.Version, 0x13)
+		}
+	})
+	t.Run("deserializing format version 0 settings", func(t *testing.T) {
+		serialized := testSettings.Serialize()
+		deserialized, err := SettingsFromBytes(serialized[1:])
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if !deserialized.Equal(testSettings) {
+			t.Errorf("deserialized settings are not as expected, got: %+v, want: %+v", deserialized, testSettings)
+		}
+	})
+	t.Run("deserializing settings with version", func(t *testing.T) {
+		settings := testSettings
+		settings.Version = 0x10
+		deserialized, err := SettingsFromBytes(settings.Serialize())
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if deserialized.Version != 0x10 {
+			t.Errorf("deserialized version is not as expected: got %d, want %d", deserialized.Version, 0x10)
+		}
+	})
+	t.Run("deserializing too short byte slice fails", func(t *testing.T) {
+		for _, length := range []int{0, 1, LegacySerializedSettingsLength - 1} {
+			if _, err := SettingsFromBytes(make([]byte, length)); err == nil {
+				t.Errorf("deserializing %d bytes should have failed", length)
+			}
+		}
+	})
+	t.Run("deserializing threads above 255 does not truncate", func(t *testing.T) {
+		serialized := testSettings.Serialize()
+		binary.LittleEndian.PutUint16(serialized[9:11], 0x0504)
+		deserialized, err := SettingsFromBytes(serialized)
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if deserialized.Threads != 4 {
+			t.Errorf("deserialized threads are not as expected, got: %d, want: %d", deserialized.Threads, 4)
+		}
+		if deserialized.Variant != Variant(5) {
+			t.Errorf("deserialized variant is not as expected, got: %d, want: %d", deserialized.Variant, 5)
+		}
+		if err = deserialized.Validate(); err == nil {
+			t.Error("settings with overflowing threads should not be valid")
+		}
+	})
 	t.Run("deserializing custom settings", func(t *testing.T) {
 		settings := NewSettings(123, 5, 8, 123, 321)
 		serialized := settings.Serialize()
-		deserialized := SettingsFromBytes(serialized)
+		deserialized, err := SettingsFromBytes(serialized)
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
 		if settings.Memory != deserialized.Memory {
 			t.Errorf("deserialized settings for memory is not as expected: got %d, want %d", deserialized.Memory,
 				settings.Memory)
@@ -159,6 +538,197 @@ func TestSettingsFromBytes(t *testing.T) {
 	})
 }
 
+func TestSettings_SerializeRoundTrip(t *testing.T) {
+	roundTrip := func(q quickSettings) bool {
+		want := Settings(q)
+		got, err := SettingsFromBytes(want.Serialize())
+		if err != nil {
+			t.Logf("failed to deserialize settings: %s", err)
+			return false
+		}
+		gotBE, err := SettingsFromBytesBigEndian(want.SerializeBigEndian())
+		if err != nil {
+			t.Logf("failed to deserialize big-endian settings: %s", err)
+			return false
+		}
+		return got == want && gotBE == want
+	}
+	t.Run("random valid settings survive a round trip", func(t *testing.T) {
+		if err := quick.Check(roundTrip, nil); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("random valid settings are valid after a round trip", func(t *testing.T) {
+		isValid := func(q quickSettings) bool {
+			settings, err := SettingsFromBytes(Settings(q).Serialize())
+			return err == nil && settings.Validate() == nil
+		}
+		if err := quick.Check(isValid, nil); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("serialized length matches the settings length", func(t *testing.T) {
+		hasLength := func(q quickSettings) bool {
+			settings := Settings(q)
+			return len(settings.Serialize()) == SerializedSettingsLength &&
+				uint64(settings.HashLength()) == SerializedSettingsLength+settings.payloadLength()
+		}
+		if err := quick.Check(hasLength, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// quickSettings generates random, valid Settings for property tests with testing/quick.
+type quickSettings Settings
+
+// Generate implements the quick.Generator interface. Threads covers the full uint8 range, since
+// the serialized format stores it in a single byte, the former high byte of a uint16 field now
+// holds the Variant and the flags. Version is always set, since a zero Version is serialized as
+// the current Argon2 version and would not survive the round trip unchanged.
+func (quickSettings) Generate(r *rand.Rand, _ int) reflect.Value {
+	threads := uint8(1 + r.Intn(math.MaxUint8))
+	settings := Settings{
+		Memory:      MinMemoryPerThread*uint32(threads) + uint32(r.Intn(1<<20)),
+		Time:        MinTime + uint32(r.Intn(16)),
+		Threads:     threads,
+		SaltLength:  MinSaltLength + uint32(r.Intn(MaxSaltLength-MinSaltLength+1)),
+		KeyLength:   MinKeyLength + uint32(r.Intn(MaxKeyLength-MinKeyLength+1)),
+		Variant:     Variant(r.Intn(int(VariantI) + 1)),
+		Version:     DefaultSettings.version(),
+		Keyed:       r.Intn(2) == 1,
+		Timestamped: r.Intn(2) == 1,
+	}
+	return reflect.ValueOf(quickSettings(settings))
+}
+
+func TestSettings_Validate(t *testing.T) {
+	t.Run("default and test settings are valid", func(t *testing.T) {
+		if err := DefaultSettings.Validate(); err != nil {
+			t.Errorf("default settings should be valid, got: %s", err)
+		}
+		if err := testSettings.Validate(); err != nil {
+			t.Errorf("test settings should be valid, got: %s", err)
+		}
+	})
+	t.Run("maximum costs are valid", func(t *testing.T) {
+		settings := NewSettings(MaxMemory, MaxTime, 1, 16, 32)
+		if err := settings.Validate(); err != nil {
+			t.Errorf("maximum costs should be valid, got: %s", err)
+		}
+	})
+	t.Run("maximum lengths are valid", func(t *testing.T) {
+		settings := NewSettings(8, 1, 1, MaxSaltLength, MaxKeyLength)
+		if err := settings.Validate(); err != nil {
+			t.Errorf("maximum lengths should be valid, got: %s", err)
+		}
+	})
+	t.Run("minimum settings are valid", func(t *testing.T) {
+		settings := NewSettings(8, 1, 1, 8, 4)
+		if err := settings.Validate(); err != nil {
+			t.Errorf("minimum settings should be valid, got: %s", err)
+		}
+	})
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			field  string
+			modify func(*Settings)
+		}{
+			{"zero threads", "Threads", func(s *Settings) { s.Threads = 0 }},
+			{"zero time", "Time", func(s *Settings) { s.Time = 0 }},
+			{"too little memory per thread", "Memory", func(s *Settings) { s.Memory = 8*uint32(s.Threads) - 1 }},
+			{"too short salt", "SaltLength", func(s *Settings) { s.SaltLength = 7 }},
+			{"too short key", "KeyLength", func(s *Settings) { s.KeyLength = 3 }},
+			{"too long salt", "SaltLength", func(s *Settings) { s.SaltLength = MaxSaltLength + 1 }},
+			{"too long key", "KeyLength", func(s *Settings) { s.KeyLength = MaxKeyLength + 1 }},
+			{"huge salt", "SaltLength", func(s *Settings) { s.SaltLength = 4294967295 }},
+			{"too much memory", "Memory", func(s *Settings) { s.Memory = MaxMemory + 1 }},
+			{"huge memory", "Memory", func(s *Settings) { s.Memory = 4294967295 }},
+			{"too many iterations", "Time", func(s *Settings) { s.Time = MaxTime + 1 }},
+			{"unknown variant", "Variant", func(s *Settings) { s.Variant = 99 }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				settings := testSettings
+				tt.modify(&settings)
+				err := settings.Validate()
+				if err == nil {
+					t.Fatal("validation of invalid settings should have failed")
+				}
+				var settingErr *InvalidSettingError
+				if !errors.As(err, &settingErr) {
+					t.Fatalf("validation error is not an InvalidSettingError, got: %T", err)
+				}
+				if settingErr.Field != tt.field {
+					t.Errorf("validation error field is not as expected, got: %s, want: %s", settingErr.Field,
+						tt.field)
+				}
+			})
+		}
+	})
+	t.Run("unsupported variants are rejected", func(t *testing.T) {
+		for _, variant := range []Variant{VariantD, 99} {
+			settings := testFastSettings
+			settings.Variant = variant
+			err := settings.Validate()
+			if !errors.Is(err, ErrUnsupportedVariant) {
+				t.Errorf("validation of %s should have failed with unsupported variant, got: %v", variant, err)
+			}
+			var settingErr *InvalidSettingError
+			if !errors.As(err, &settingErr) || settingErr.Field != "Variant" {
+				t.Errorf("validation error of %s should identify the variant, got: %v", variant, err)
+			}
+		}
+	})
+	t.Run("short lengths are valid in lenient mode", func(t *testing.T) {
+		settings := NewSettings(8, 1, 1, 8, 4)
+		if err := settings.Validate(); err != nil {
+			t.Errorf("short lengths should be valid in lenient mode, got: %s", err)
+		}
+		warnings, err := settings.ValidateWithStrictness(LenientSettings)
+		if err != nil {
+			t.Errorf("short lengths should be valid in lenient mode, got: %s", err)
+		}
+		if len(warnings) != 2 || warnings[0].Field != "SaltLength" || warnings[1].Field != "KeyLength" {
+			t.Errorf("short lengths should be reported as warnings in lenient mode, got: %v", warnings)
+		}
+	})
+	t.Run("short lengths are rejected in strict mode", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			field    string
+			settings Settings
+		}{
+			{"short salt", "SaltLength", NewSettings(8, 1, 1, RecommendedSaltLength-1, RecommendedKeyLength)},
+			{"short key", "KeyLength", NewSettings(8, 1, 1, RecommendedSaltLength, RecommendedKeyLength-1)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var settingErr *InvalidSettingError
+				_, err := tt.settings.ValidateWithStrictness(StrictSettings)
+				if !errors.As(err, &settingErr) || settingErr.Field != tt.field {
+					t.Errorf("validation should have failed for %s in strict mode, got: %v", tt.field, err)
+				}
+			})
+		}
+		warnings, err := testFastSettings.ValidateWithStrictness(StrictSettings)
+		if err != nil || len(warnings) != 0 {
+			t.Errorf("recommended lengths should be valid in strict mode, got: %v, %v", warnings, err)
+		}
+	})
+	t.Run("invalid settings fail regardless of strictness", func(t *testing.T) {
+		settings := NewSettings(0, 1, 1, 8, 4)
+		for _, strictness := range []Strictness{LenientSettings, StrictSettings} {
+			var settingErr *InvalidSettingError
+			if _, err := settings.ValidateWithStrictness(strictness); !errors.As(err, &settingErr) ||
+				settingErr.Field != "Memory" {
+				t.Errorf("validation of invalid memory should fail, got: %v", err)
+			}
+		}
+	})
+}
+
 func BenchmarkSettings_Serialize(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
@@ -170,6 +740,193 @@ func BenchmarkSettingsFromBytes(b *testing.B) {
 	serialized := DefaultSettings.Serialize()
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		SettingsFromBytes(serialized)
+		_, _ = SettingsFromBytes(serialized)
 	}
 }
+
+func TestSettings_Equal(t *testing.T) {
+	t.Run("equal settings", func(t *testing.T) {
+		if !testSettings.Equal(testSettings) {
+			t.Error("settings should be equal to themselves")
+		}
+	})
+	t.Run("deserialized settings are equal to the original", func(t *testing.T) {
+		settings := NewSettings(123, 5, 8, 123, 321)
+		deserialized, err := SettingsFromBytes(settings.Serialize())
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if !settings.Equal(deserialized) {
+			t.Errorf("deserialized settings should be equal, got: %+v, want: %+v", deserialized, settings)
+		}
+	})
+	t.Run("zero version is equal to current version", func(t *testing.T) {
+		settings := testSettings
+		settings.Version = 0
+		other := testSettings
+		other.Version = 0x13
+		if !settings.Equal(other) {
+			t.Error("settings with zero version should be equal to settings with the current version")
+		}
+	})
+	t.Run("only keyed flag differs", func(t *testing.T) {
+		keyed := testSettings
+		keyed.Keyed = true
+		if testSettings.Equal(keyed) {
+			t.Error("keyed and unkeyed settings should not be equal")
+		}
+		deserialized, err := SettingsFromBytes(keyed.Serialize())
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if !keyed.Equal(deserialized) {
+			t.Errorf("deserialized keyed settings should be equal, got: %+v, want: %+v", deserialized, keyed)
+		}
+	})
+	t.Run("only timestamped flag differs", func(t *testing.T) {
+		timestamped := testSettings
+		timestamped.Timestamped = true
+		if testSettings.Equal(timestamped) {
+			t.Error("timestamped and plain settings should not be equal")
+		}
+		deserialized, err := SettingsFromBytes(timestamped.Serialize())
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if !timestamped.Equal(deserialized) {
+			t.Errorf("deserialized timestamped settings should be equal, got: %+v, want: %+v", deserialized,
+				timestamped)
+		}
+		if deserialized.Variant != testSettings.Variant {
+			t.Errorf("variant is not as expected, got: %d, want: %d", deserialized.Variant, testSettings.Variant)
+		}
+	})
+	t.Run("only threads differ", func(t *testing.T) {
+		settings := NewSettings(65536, 2, 4, 16, 32)
+		other := NewSettings(65536, 2, 255, 16, 32)
+		if settings.Equal(other) {
+			t.Error("settings with different threads should not be equal")
+		}
+		deserialized, err := SettingsFromBytes(other.Serialize())
+		if err != nil {
+			t.Fatalf("failed to deserialize settings: %s", err)
+		}
+		if !other.Equal(deserialized) {
+			t.Errorf("deserialized settings should be equal, got threads: %d, want: %d",
+				deserialized.Threads, other.Threads)
+		}
+		if settings.Equal(deserialized) {
+			t.Error("deserialized settings with different threads should not be equal")
+		}
+	})
+	t.Run("differing fields", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(*Settings)
+		}{
+			{"memory", func(s *Settings) { s.Memory++ }},
+			{"time", func(s *Settings) { s.Time++ }},
+			{"salt length", func(s *Settings) { s.SaltLength++ }},
+			{"key length", func(s *Settings) { s.KeyLength++ }},
+			{"variant", func(s *Settings) { s.Variant = VariantI }},
+			{"version", func(s *Settings) { s.Version = 0x10 }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				other := testSettings
+				tt.modify(&other)
+				if testSettings.Equal(other) {
+					t.Errorf("settings with different %s should not be equal", tt.name)
+				}
+			})
+		}
+	})
+}
+
+func FuzzSettingsFromBytes(f *testing.F) {
+	f.Add(testSettings.Serialize())
+	f.Add(testDerived[:LegacySerializedSettingsLength])
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		settings, err := SettingsFromBytes(data)
+		if err != nil {
+			if len(data) >= LegacySerializedSettingsLength {
+				t.Fatalf("deserializing %d bytes should not fail: %s", len(data), err)
+			}
+			return
+		}
+		offset := 0
+		if len(data) >= SerializedSettingsLength && data[0] == SerializedFormatVersion {
+			offset = 1
+		}
+		serialized := settings.Serialize()[1-offset : 1+LegacySerializedSettingsLength]
+		if !bytes.Equal(serialized, data[:offset+LegacySerializedSettingsLength]) {
+			t.Fatalf("serialized settings do not round-trip, got: %x, want: %x", serialized,
+				data[:offset+LegacySerializedSettingsLength])
+		}
+	})
+}
+
+func TestSettings_StepToward(t *testing.T) {
+	current := NewSettingsMiB(19, 2, 1, 16, 32)
+	target := NewSettingsMiB(64, 3, 4, 16, 32)
+	step := Settings{Memory: 16 * 1024, Time: 1, Threads: 1}
+
+	t.Run("step increases the cost by one step", func(t *testing.T) {
+		next := current.StepToward(target, step)
+		want := NewSettingsMiB(35, 3, 2, 16, 32)
+		if !next.Equal(want) {
+			t.Errorf("next step is not as expected, got: %+v, want: %+v", next, want)
+		}
+	})
+	t.Run("steps reach the target", func(t *testing.T) {
+		settings := current
+		steps := 0
+		for settings.weakerThan(target) {
+			settings = settings.StepToward(target, step)
+			if err := settings.Validate(); err != nil {
+				t.Fatalf("step %d is invalid: %s", steps, err)
+			}
+			steps++
+			if steps > 10 {
+				t.Fatal("target was not reached within 10 steps")
+			}
+		}
+		if steps != 3 {
+			t.Errorf("number of steps is not as expected, got: %d, want: %d", steps, 3)
+		}
+		if !settings.Equal(target) {
+			t.Errorf("final settings are not as expected, got: %+v, want: %+v", settings, target)
+		}
+	})
+	t.Run("zero step reaches the target right away", func(t *testing.T) {
+		next := current.StepToward(target, Settings{})
+		if !next.Equal(target) {
+			t.Errorf("next step is not as expected, got: %+v, want: %+v", next, target)
+		}
+	})
+	t.Run("stronger parameters are never lowered", func(t *testing.T) {
+		strong := NewSettingsMiB(128, 1, 8, 32, 64)
+		next := strong.StepToward(target, step)
+		want := NewSettingsMiB(128, 2, 8, 32, 64)
+		if !next.Equal(want) {
+			t.Errorf("next step is not as expected, got: %+v, want: %+v", next, want)
+		}
+	})
+	t.Run("step does not overflow", func(t *testing.T) {
+		huge := Settings{Memory: math.MaxUint32, Time: math.MaxUint32, Threads: math.MaxUint8}
+		next := current.StepToward(target, huge)
+		if !next.Equal(target) {
+			t.Errorf("next step is not as expected, got: %+v, want: %+v", next, target)
+		}
+	})
+	t.Run("step adopts the target variant and clears flags", func(t *testing.T) {
+		legacy := current
+		legacy.Variant = VariantI
+		legacy.Keyed = true
+		next := legacy.StepToward(target, step)
+		if next.Variant != target.Variant || next.Keyed {
+			t.Errorf("next step should use the target variant without flags, got: %+v", next)
+		}
+	})
+}
@@ -5,39 +5,175 @@
 package argon2
 
 import (
+	"bytes"
 	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 )
 
 // Scan implements the sql.Scanner interface so Argon2 can be read from databases
 // transparently. Currently, database types that map to string and []byte are supported.
+// The salt and key lengths are read from the settings header of every stored value, so a column
+// may contain hashes with different salt or key lengths, e.g. from different eras of a system;
+// no global default is assumed. Hashes with a salt or key length above MaxSaltLength or
+// MaxKeyLength, or a Memory or Time above MaxMemory or MaxTime, are rejected. Hashes with an
+// unsupported Variant are rejected with an error wrapping ErrUnsupportedVariant, so that they are
+// noticed when they are read instead of failing every login. A NULL or empty value results in a
+// nil Argon2.
+//
+// Besides raw bytes, Scan accepts hashes that are stored as PHC string or hex or base64 encoded,
+// e.g. in a varchar column that was populated by another language. The detection is done in the
+// following order of precedence:
+//  1. If the stored value is a structurally valid raw hash, it is used as is.
+//  2. If the stored value starts with "$argon2", it is parsed as PHC string using ParsePHC and
+//     converted into the native byte layout. If parsing fails, its error is returned.
+//  3. If the stored value has an even length and consists of hex digits only, it is hex
+//     decoded. If the result is a structurally valid hash, it is used.
+//  4. The stored value is decoded using the standard and the URL-safe base64 alphabet, each
+//     with and without padding. The first decoded value that is a structurally valid hash
+//     is used.
+//
+// If none of the decodings results in a valid hash, the stored value is treated as raw bytes
+// and the usual length errors are returned. Since hex digits are valid base64 characters as
+// well, hex is tried before base64. Value always writes raw bytes; use TextArgon2 to write PHC
+// strings instead.
 func (a *Argon2) Scan(src any) error {
 	switch src := src.(type) {
 	case nil:
+		*a = nil
 		return nil
 	case string:
 		return a.Scan([]byte(src))
 	case []byte:
 		if len(src) == 0 {
+			*a = nil
 			return nil
 		}
-		if len(src) < SerializedSettingsLength {
-			return fmt.Errorf("invalid Argon2 hash length, got: %d, expected: %d", len(src), SerializedSettingsLength)
+		if _, ok := parseLayout(src); !ok {
+			if bytes.HasPrefix(src, []byte(phcPrefix)) {
+				hash, err := ParsePHC(string(src))
+				if err != nil {
+					return err
+				}
+				src = hash
+			} else if decoded, ok := decodeHexHash(src); ok {
+				src = decoded
+			} else if decoded, ok = decodeBase64Hash(src); ok {
+				src = decoded
+			}
+		}
+		if len(src) < LegacySerializedSettingsLength {
+			return fmt.Errorf("%w, got: %d, expected at least: %d", ErrInvalidHashLength, len(src),
+				LegacySerializedSettingsLength)
 		}
-		settings := SettingsFromBytes(src[:SerializedSettingsLength])
-		if len(src) != SerializedSettingsLength+int(settings.SaltLength+settings.KeyLength) {
-			return fmt.Errorf("invalid Argon2 hash length, got: %d, expected: %d", len(src),
-				SerializedSettingsLength+int(settings.SaltLength+settings.KeyLength))
+		settings := settingsFromHeader(src)
+		if err := settings.validateHeader(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidHash, err)
+		}
+		if _, ok := parseLayout(src); !ok {
+			return fmt.Errorf("%w, got: %d, expected: %d", ErrInvalidHashLength, len(src),
+				settings.HashLength())
 		}
 		*a = src
 	default:
-		return fmt.Errorf("unable to scan type %T into Argon2", src)
+		return fmt.Errorf("%w: unable to scan type %T into Argon2", ErrUnsupportedScanType, src)
 	}
 	return nil
 }
 
+// decodeHexHash tries to decode the given hex encoded data. It returns the decoded value if the
+// data has an even length, consists of hex digits only and decodes to a structurally valid hash.
+func decodeHexHash(src []byte) ([]byte, bool) {
+	if len(src)%2 != 0 {
+		return nil, false
+	}
+	decoded := make([]byte, hex.DecodedLen(len(src)))
+	if _, err := hex.Decode(decoded, src); err != nil {
+		return nil, false
+	}
+	if _, ok := parseLayout(decoded); !ok {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// base64Encodings are the base64 encodings that Scan tries to decode stored values with, in the
+// order of precedence.
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// decodeBase64Hash tries to decode the given base64 encoded data using the base64Encodings. It
+// returns the first decoded value that is a structurally valid hash and false if there is none.
+func decodeBase64Hash(src []byte) ([]byte, bool) {
+	for _, encoding := range base64Encodings {
+		decoded := make([]byte, encoding.DecodedLen(len(src)))
+		n, err := encoding.Decode(decoded, src)
+		if err != nil {
+			continue
+		}
+		if _, ok := parseLayout(decoded[:n]); ok {
+			return decoded[:n], true
+		}
+	}
+	return nil, false
+}
+
 // Value implements the driver.Valuer interface so that Argon2 can be written to databases
-// transparently. Currently, Argon2 maps to a byte slice.
+// transparently. Currently, Argon2 maps to a byte slice. An empty Argon2 maps to SQL NULL, so
+// that it round-trips with Scan for optional password columns. To write the hash as PHC string
+// instead, e.g. into a text column, convert it to TextArgon2.
 func (a Argon2) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
 	return []byte(a), nil
 }
+
+// TextArgon2 is an Argon2 hash that is stored in the PHC string format in databases.
+//
+// Argon2 is stored as raw bytes, which is awkward for text columns and unreadable in database
+// dumps. TextArgon2 is stored as PHC string instead, e.g. "$argon2id$v=19$m=65536,t=3,p=4$...",
+// which is portable across languages and libraries. Since it shares the underlying type with
+// Argon2, it can be converted back and forth, e.g. Argon2(text).Validate(password). Like
+// MarshalPHC, TextArgon2 drops the creation timestamp of timestamped hashes.
+type TextArgon2 Argon2
+
+// Scan implements the sql.Scanner interface so TextArgon2 can be read from text columns. The
+// stored value is parsed as PHC string using ParsePHC. Database types that map to string and
+// []byte are supported. A NULL or empty value results in an empty hash.
+func (t *TextArgon2) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return t.Scan(string(src))
+	case string:
+		if src == "" {
+			*t = nil
+			return nil
+		}
+		hash, err := ParsePHC(src)
+		if err != nil {
+			return err
+		}
+		*t = TextArgon2(hash)
+	default:
+		return fmt.Errorf("%w: unable to scan type %T into TextArgon2", ErrUnsupportedScanType, src)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface so that TextArgon2 can be written to text columns.
+// The hash is encoded as PHC string using MarshalPHC. An empty hash maps to SQL NULL.
+func (t TextArgon2) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	return Argon2(t).MarshalPHC()
+}
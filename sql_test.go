@@ -6,6 +6,11 @@ package argon2
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
 	"testing"
 )
 
@@ -46,14 +51,122 @@ func TestArgon2_Scan(t *testing.T) {
 	})
 	t.Run("scan with invalid byte array", func(t *testing.T) {
 		var argon Argon2
-		if err := (&argon).Scan([]byte{0x00, 0x00, 0x00}); err == nil {
-			t.Fatal("scan should have failed with invalid byte array")
+		err := (&argon).Scan([]byte{0x00, 0x00, 0x00})
+		if !errors.Is(err, ErrInvalidHashLength) {
+			t.Fatalf("scan should have failed with invalid hash length, got: %v", err)
 		}
 	})
 	t.Run("scan with too short byte array", func(t *testing.T) {
 		var argon Argon2
-		if err := (&argon).Scan(testDerived[:len(testDerived)-1]); err == nil {
-			t.Fatal("scan should have failed with too short byte array")
+		err := (&argon).Scan(testDerived[:len(testDerived)-1])
+		if !errors.Is(err, ErrInvalidHashLength) {
+			t.Fatalf("scan should have failed with invalid hash length, got: %v", err)
+		}
+	})
+	t.Run("scan with too long salt length fails", func(t *testing.T) {
+		var argon Argon2
+		header := testFastSettings
+		header.SaltLength = 4294967295
+		err := (&argon).Scan(header.Serialize())
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Fatalf("scan should have failed with invalid hash, got: %v", err)
+		}
+		var settingErr *InvalidSettingError
+		if !errors.As(err, &settingErr) || settingErr.Field != "SaltLength" {
+			t.Errorf("scan error should identify the salt length, got: %v", err)
+		}
+	})
+	t.Run("scan with too long key length fails", func(t *testing.T) {
+		var argon Argon2
+		header := testFastSettings
+		header.KeyLength = MaxKeyLength + 1
+		data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+		if err := (&argon).Scan(data); !errors.Is(err, ErrInvalidHash) {
+			t.Fatalf("scan should have failed with invalid hash, got: %v", err)
+		}
+	})
+	t.Run("scan with excessive costs fails", func(t *testing.T) {
+		for _, field := range []string{"Memory", "Time"} {
+			header := testFastSettings
+			if field == "Memory" {
+				header.Memory = math.MaxUint32
+			} else {
+				header.Time = MaxTime + 1
+			}
+			data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+			var argon Argon2
+			err := (&argon).Scan(data)
+			var settingErr *InvalidSettingError
+			if !errors.Is(err, ErrInvalidHash) || !errors.As(err, &settingErr) || settingErr.Field != field {
+				t.Errorf("scan with excessive %s should have failed with invalid hash, got: %v", field, err)
+			}
+		}
+	})
+	t.Run("scan hashes with mixed salt lengths", func(t *testing.T) {
+		saltLengths := []uint32{16, 32, 8, 16, 32}
+		var rows []Argon2
+		for _, saltLength := range saltLengths {
+			settings := testFastSettings
+			settings.SaltLength = saltLength
+			derived, err := Derive(testPassPhrase, settings)
+			if err != nil {
+				t.Fatalf("failed to derive hash: %s", err)
+			}
+			rows = append(rows, derived)
+		}
+		for i, row := range rows {
+			var argon Argon2
+			if err := (&argon).Scan([]byte(row)); err != nil {
+				t.Fatalf("failed to scan hash %d: %s", i, err)
+			}
+			settings, err := argon.Settings()
+			if err != nil {
+				t.Fatalf("failed to read settings of hash %d: %s", i, err)
+			}
+			if settings.SaltLength != saltLengths[i] || len(argon.Salt()) != int(saltLengths[i]) {
+				t.Errorf("salt length of hash %d is not as expected, got: %d, want: %d", i, settings.SaltLength,
+					saltLengths[i])
+			}
+			if !argon.Validate(testPassPhrase) {
+				t.Errorf("scanned hash %d with salt length %d should be valid", i, settings.SaltLength)
+			}
+		}
+	})
+	t.Run("scan hash with the salt length of another record fails", func(t *testing.T) {
+		short, long := testFastSettings, testFastSettings
+		long.SaltLength = 32
+		derived, err := Derive(testPassPhrase, short)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		data := append(long.Serialize(), derived[SerializedSettingsLength:]...)
+		var argon Argon2
+		if err = (&argon).Scan(data); !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("scan with mismatching salt length should fail with invalid hash length, got: %v", err)
+		}
+	})
+	t.Run("scan with unsupported variant fails", func(t *testing.T) {
+		for _, variant := range []Variant{VariantD, 63} {
+			header := testFastSettings
+			header.Variant = variant
+			data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+			var argon Argon2
+			err := (&argon).Scan(data)
+			if !errors.Is(err, ErrUnsupportedVariant) || !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("scan of %s should have failed with unsupported variant, got: %v", variant, err)
+			}
+			if argon != nil {
+				t.Errorf("scan of %s should not have assigned the hash", variant)
+			}
+		}
+	})
+	t.Run("scan with short key length succeeds", func(t *testing.T) {
+		header := testFastSettings
+		header.KeyLength = RecommendedKeyLength - 1
+		data := append(header.Serialize(), make([]byte, header.SaltLength+header.KeyLength)...)
+		var argon Argon2
+		if err := (&argon).Scan(data); err != nil {
+			t.Errorf("scan with short key length should succeed, got: %s", err)
 		}
 	})
 	t.Run("scan with valid string", func(t *testing.T) {
@@ -71,10 +184,131 @@ func TestArgon2_Scan(t *testing.T) {
 			t.Errorf("argon2 from scan does not match expected value, got: %x, want: %x", argon, testDerived)
 		}
 	})
+	t.Run("scan with base64 encoded string", func(t *testing.T) {
+		for _, encoding := range base64Encodings {
+			var argon Argon2
+			if err := (&argon).Scan(encoding.EncodeToString(testDerived)); err != nil {
+				t.Fatalf("failed to scan base64 encoded string: %s", err)
+			}
+			if !bytes.Equal(argon, testDerived) {
+				t.Errorf("argon2 from scan does not match expected value, got: %x, want: %x", argon, testDerived)
+			}
+		}
+	})
+	t.Run("scan with base64 encoded byte array", func(t *testing.T) {
+		var argon Argon2
+		if err := (&argon).Scan([]byte(base64.StdEncoding.EncodeToString(testDerived))); err != nil {
+			t.Fatalf("failed to scan base64 encoded byte array: %s", err)
+		}
+		if !bytes.Equal(argon, testDerived) {
+			t.Errorf("argon2 from scan does not match expected value, got: %x, want: %x", argon, testDerived)
+		}
+	})
+	t.Run("scan with hex encoded string", func(t *testing.T) {
+		for _, encoded := range []string{hex.EncodeToString(testDerived), strings.ToUpper(hex.EncodeToString(testDerived))} {
+			var argon Argon2
+			if err := (&argon).Scan(encoded); err != nil {
+				t.Fatalf("failed to scan hex encoded string: %s", err)
+			}
+			if !bytes.Equal(argon, testDerived) {
+				t.Errorf("argon2 from scan does not match expected value, got: %x, want: %x", []byte(argon), testDerived)
+			}
+		}
+	})
+	t.Run("scan with hex encoded byte array", func(t *testing.T) {
+		var argon Argon2
+		if err := (&argon).Scan([]byte(Argon2(testDerived).Hex())); err != nil {
+			t.Fatalf("failed to scan hex encoded byte array: %s", err)
+		}
+		if !bytes.Equal(argon, testDerived) {
+			t.Errorf("argon2 from scan does not match expected value, got: %x, want: %x", []byte(argon), testDerived)
+		}
+	})
+	t.Run("scan with hex encoded invalid hash fails", func(t *testing.T) {
+		var argon Argon2
+		if err := (&argon).Scan(hex.EncodeToString(testDerived[:len(testDerived)-2])); err == nil {
+			t.Fatal("scan of hex encoded invalid hash should have failed")
+		}
+	})
+	t.Run("scan precedence of raw, hex and base64", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input []byte
+		}{
+			{"raw", testDerived},
+			{"hex", []byte(hex.EncodeToString(testDerived))},
+			{"base64", []byte(base64.StdEncoding.EncodeToString(testDerived))},
+			{"raw url base64", []byte(base64.RawURLEncoding.EncodeToString(testDerived))},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var argon Argon2
+				if err := (&argon).Scan(tt.input); err != nil {
+					t.Fatalf("failed to scan %s input: %s", tt.name, err)
+				}
+				if !bytes.Equal(argon, testDerived) {
+					t.Errorf("argon2 from scan does not match expected value, got: %x, want: %x", []byte(argon),
+						testDerived)
+				}
+			})
+		}
+	})
+	t.Run("scan with base64 encoded invalid hash fails", func(t *testing.T) {
+		var argon Argon2
+		err := (&argon).Scan(base64.StdEncoding.EncodeToString(testDerived[:len(testDerived)-2]))
+		if err == nil {
+			t.Fatal("scan of base64 encoded invalid hash should have failed")
+		}
+	})
+	t.Run("scan with PHC string", func(t *testing.T) {
+		var argon Argon2
+		if err := (&argon).Scan(testPHC); err != nil {
+			t.Fatalf("failed to scan PHC string: %s", err)
+		}
+		if !argon.Validate(testPassPhrase) {
+			t.Error("scanned hash is not valid but should be")
+		}
+	})
+	t.Run("scan with PHC byte array", func(t *testing.T) {
+		var argon Argon2
+		if err := (&argon).Scan([]byte(testReferencePHC)); err != nil {
+			t.Fatalf("failed to scan PHC byte array: %s", err)
+		}
+		if !argon.Validate("password") {
+			t.Error("scanned hash is not valid but should be")
+		}
+	})
+	t.Run("scanned PHC string round-trips through value", func(t *testing.T) {
+		var argon Argon2
+		if err := (&argon).Scan(testReferencePHC); err != nil {
+			t.Fatalf("failed to scan PHC string: %s", err)
+		}
+		value, err := TextArgon2(argon).Value()
+		if err != nil {
+			t.Fatalf("failed to get PHC value: %s", err)
+		}
+		if value != testReferencePHC {
+			t.Errorf("PHC value does not match, got: %v, want: %s", value, testReferencePHC)
+		}
+		raw, err := argon.Value()
+		if err != nil {
+			t.Fatalf("failed to get raw value: %s", err)
+		}
+		if !bytes.Equal(raw.([]byte), argon) {
+			t.Errorf("raw value does not match, got: %x, want: %x", raw, []byte(argon))
+		}
+	})
+	t.Run("scan with invalid PHC string fails", func(t *testing.T) {
+		var argon Argon2
+		if err := (&argon).Scan("$argon2id$invalid"); err == nil {
+			t.Error("scan with invalid PHC string should fail")
+		}
+	})
 	t.Run("scan with unsupported type", func(t *testing.T) {
 		var argon Argon2
-		if err := (&argon).Scan(123); err == nil {
-			t.Fatal("scan should have failed with unsupported type")
+		err := (&argon).Scan(123)
+		if !errors.Is(err, ErrUnsupportedScanType) {
+			t.Fatalf("scan should have failed with unsupported type, got: %v", err)
 		}
 	})
 }
@@ -82,9 +316,31 @@ func TestArgon2_Scan(t *testing.T) {
 func TestArgon2_Value(t *testing.T) {
 	t.Run("value with nil value", func(t *testing.T) {
 		var argon Argon2
-		value, _ := argon.Value()
-		if !bytes.Equal(value.([]byte), []byte{}) {
-			t.Fatal("argon2 with nil value did not return empty byte slice")
+		value, err := argon.Value()
+		if err != nil {
+			t.Fatalf("failed to get value: %s", err)
+		}
+		if value != nil {
+			t.Fatalf("argon2 with nil value did not return SQL NULL, got: %v", value)
+		}
+	})
+	t.Run("value with empty value", func(t *testing.T) {
+		value, err := Argon2{}.Value()
+		if err != nil {
+			t.Fatalf("failed to get value: %s", err)
+		}
+		if value != nil {
+			t.Fatalf("argon2 with empty value did not return SQL NULL, got: %v", value)
+		}
+	})
+	t.Run("nil value round-trips through scan", func(t *testing.T) {
+		value, _ := Argon2(nil).Value()
+		argon := Argon2(testDerived)
+		if err := (&argon).Scan(value); err != nil {
+			t.Fatalf("failed to scan nil value: %s", err)
+		}
+		if len(argon) != 0 {
+			t.Errorf("argon2 should be empty after scanning SQL NULL, got: %x", argon)
 		}
 	})
 	t.Run("value with valid value", func(t *testing.T) {
@@ -102,3 +358,115 @@ func TestArgon2_Value(t *testing.T) {
 		}
 	})
 }
+
+func FuzzArgon2_Scan(f *testing.F) {
+	f.Add(testDerived)
+	f.Add([]byte{})
+	f.Add(make([]byte, SerializedSettingsLength))
+	f.Add(append(testFastSettings.Serialize(), make([]byte, 48)...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var argon Argon2
+		if err := (&argon).Scan(data); err != nil {
+			return
+		}
+		if len(data) == 0 {
+			if argon != nil {
+				t.Fatalf("scanning empty data should result in a nil hash, got: %x", argon)
+			}
+			return
+		}
+		if _, ok := parseLayout(argon); !ok {
+			t.Fatalf("scanned hash has an invalid layout: %x", argon)
+		}
+		if _, err := argon.Settings(); err != nil {
+			t.Fatalf("failed to extract settings from scanned hash: %s", err)
+		}
+	})
+}
+
+func TestTextArgon2_Scan(t *testing.T) {
+	t.Run("scan with PHC string", func(t *testing.T) {
+		var text TextArgon2
+		if err := (&text).Scan(testPHC); err != nil {
+			t.Fatalf("failed to scan PHC string: %s", err)
+		}
+		if !Argon2(text).Validate(testPassPhrase) {
+			t.Error("scanned hash is not valid but should be")
+		}
+	})
+	t.Run("scan with PHC byte array", func(t *testing.T) {
+		var text TextArgon2
+		if err := (&text).Scan([]byte(testReferencePHC)); err != nil {
+			t.Fatalf("failed to scan PHC byte array: %s", err)
+		}
+		if !Argon2(text).Validate("password") {
+			t.Error("scanned hash is not valid but should be")
+		}
+	})
+	t.Run("scan with nil value", func(t *testing.T) {
+		var text TextArgon2
+		if err := (&text).Scan(nil); err != nil {
+			t.Fatalf("failed to scan nil value: %s", err)
+		}
+		if text != nil {
+			t.Error("hash is not nil after scan")
+		}
+	})
+	t.Run("scan with empty string", func(t *testing.T) {
+		text := TextArgon2(testDerived)
+		if err := (&text).Scan(""); err != nil {
+			t.Fatalf("failed to scan empty string: %s", err)
+		}
+		if text != nil {
+			t.Error("hash is not nil after scan")
+		}
+	})
+	t.Run("scan with invalid PHC string fails", func(t *testing.T) {
+		var text TextArgon2
+		if err := (&text).Scan("$argon2id$invalid"); err == nil {
+			t.Error("scan with invalid PHC string should fail")
+		}
+	})
+	t.Run("scan with raw hash fails", func(t *testing.T) {
+		var text TextArgon2
+		if err := (&text).Scan(testDerived); err == nil {
+			t.Error("scan with raw hash should fail")
+		}
+	})
+	t.Run("scan with unsupported type", func(t *testing.T) {
+		var text TextArgon2
+		if err := (&text).Scan(123); !errors.Is(err, ErrUnsupportedScanType) {
+			t.Fatalf("scan should have failed with unsupported type, got: %v", err)
+		}
+	})
+}
+
+func TestTextArgon2_Value(t *testing.T) {
+	t.Run("value is the PHC string", func(t *testing.T) {
+		hash, err := ParsePHC(testPHC)
+		if err != nil {
+			t.Fatalf("failed to parse PHC string: %s", err)
+		}
+		value, err := TextArgon2(hash).Value()
+		if err != nil {
+			t.Fatalf("failed to get value: %s", err)
+		}
+		if value != testPHC {
+			t.Errorf("value is not as expected, got: %v, want: %s", value, testPHC)
+		}
+	})
+	t.Run("value with nil value", func(t *testing.T) {
+		value, err := TextArgon2(nil).Value()
+		if err != nil {
+			t.Fatalf("failed to get value: %s", err)
+		}
+		if value != nil {
+			t.Errorf("value of nil hash should be SQL NULL, got: %v", value)
+		}
+	})
+	t.Run("value with invalid hash fails", func(t *testing.T) {
+		if _, err := TextArgon2(testDerived[:len(testDerived)-2]).Value(); err == nil {
+			t.Error("value of invalid hash should fail")
+		}
+	})
+}
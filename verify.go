@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// verifyIDLengthPrefixSize is the size in bytes of the length prefix of the id of every record in
+// a stream that is read by VerifyStream.
+const verifyIDLengthPrefixSize = 2
+
+// MaxVerifyFailedIDs is the maximum number of ids of failed records that are collected in the
+// FailedIDs of a VerifyReport. The failures beyond this limit are still counted in Failed, so
+// that verifying a corrupted stream of any size does not grow the report without bound.
+const MaxVerifyFailedIDs = 100
+
+// VerifyReport summarizes the result of VerifyStream.
+//
+// Fields:
+//   - Total: The number of records that were read from the stream.
+//   - Passed: The number of hashes that matched the password returned by the lookup.
+//   - Failed: The number of hashes that did not match the password returned by the lookup.
+//   - Skipped: The number of records for which the lookup did not return a password.
+//   - FailedIDs: The ids of the first MaxVerifyFailedIDs records that failed, in the order they
+//     were read. If Failed is larger than the length of FailedIDs, the list was truncated.
+type VerifyReport struct {
+	Total     int
+	Passed    int
+	Failed    int
+	Skipped   int
+	FailedIDs []string
+}
+
+// WriteVerifyRecord writes a single record for VerifyStream to the given writer.
+//
+// A record consists of the length of the id as 2 byte unsigned integer in little-endian byte
+// order, followed by the id and the hash as it is written by WriteTo. Records can be written one
+// after another to create a stream of any size.
+//
+// Parameters:
+//   - w: The io.Writer the record is written to.
+//   - id: The id that identifies the hash, e.g. the name of a test account. It must not be longer
+//     than 65535 bytes.
+//   - hash: The Argon2 hash of the record.
+//
+// Returns:
+//   - The number of bytes written.
+//   - An error if the id is too long, an error wrapping ErrInvalidHashLength if the hash is
+//     structurally invalid, or any error returned by the writer.
+func WriteVerifyRecord(w io.Writer, id string, hash Argon2) (int64, error) {
+	if len(id) > math.MaxUint16 {
+		return 0, fmt.Errorf("failed to write verify record: id length %d exceeds %d bytes", len(id),
+			math.MaxUint16)
+	}
+	if _, ok := parseLayout(hash); !ok {
+		return 0, fmt.Errorf("failed to write verify record: %w", ErrInvalidHashLength)
+	}
+	prefix := binary.LittleEndian.AppendUint16(make([]byte, 0, verifyIDLengthPrefixSize+len(id)),
+		uint16(len(id)))
+	n, err := w.Write(append(prefix, id...))
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := hash.WriteTo(w)
+	return int64(n) + m, err
+}
+
+// VerifyStream reads records that were written by WriteVerifyRecord from the given reader and
+// verifies every hash against the password that is returned by lookup for its id.
+//
+// This is an integrity check for large sets of stored hashes, e.g. a nightly job that re-verifies
+// the hashes of known test accounts to detect silent corruption. The records are read and verified
+// one at a time and at most MaxVerifyFailedIDs ids of failed records are collected, so the memory
+// usage does not grow with the size of the stream. Records for which lookup returns false are
+// counted as skipped without executing the Argon2 KDF. A hash that is structurally valid, but uses
+// an unsupported version, is counted as failed. A hash with an unsupported variant is rejected by
+// ReadFrom and stops the verification with an error.
+//
+// Parameters:
+//   - r: The io.Reader the records are read from.
+//   - lookup: A function that returns the known password for the given id and whether it exists.
+//
+// Returns:
+//   - A VerifyReport with the counts of all records that were read, including the records that
+//     were read before an error occurred.
+//   - nil if the stream ended after a complete record, io.ErrUnexpectedEOF if it ended in the
+//     middle of a record, an error wrapping ErrInvalidHash if a hash header is invalid, or any
+//     other error returned by the reader. Since the records are not self-synchronizing, reading
+//     stops at the first error.
+func VerifyStream(r io.Reader, lookup func(id string) (password string, ok bool)) (VerifyReport, error) {
+	var report VerifyReport
+	prefix := make([]byte, verifyIDLengthPrefixSize)
+	for {
+		if _, err := io.ReadFull(r, prefix); err != nil {
+			if errors.Is(err, io.EOF) {
+				return report, nil
+			}
+			return report, fmt.Errorf("failed to read record %d: %w", report.Total, err)
+		}
+		id := make([]byte, binary.LittleEndian.Uint16(prefix))
+		if _, err := io.ReadFull(r, id); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return report, fmt.Errorf("failed to read record %d: %w", report.Total, err)
+		}
+		var hash Argon2
+		if _, err := hash.ReadFrom(r); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return report, fmt.Errorf("failed to read record %d: %w", report.Total, err)
+		}
+
+		report.Total++
+		password, ok := lookup(string(id))
+		switch {
+		case !ok:
+			report.Skipped++
+		case hash.Validate(password):
+			report.Passed++
+		default:
+			report.Failed++
+			if len(report.FailedIDs) < MaxVerifyFailedIDs {
+				report.FailedIDs = append(report.FailedIDs, string(id))
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package argon2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteVerifyRecord(t *testing.T) {
+	derived, err := Derive(testPassPhrase, testFastSettings)
+	if err != nil {
+		t.Fatalf("failed to derive hash: %s", err)
+	}
+	t.Run("write record", func(t *testing.T) {
+		buffer := bytes.NewBuffer(nil)
+		n, err := WriteVerifyRecord(buffer, "alice", derived)
+		if err != nil {
+			t.Fatalf("failed to write verify record: %s", err)
+		}
+		want := append([]byte{5, 0, 'a', 'l', 'i', 'c', 'e'}, derived...)
+		if n != int64(len(want)) {
+			t.Errorf("unexpected number of bytes written, got: %d, want: %d", n, len(want))
+		}
+		if !bytes.Equal(buffer.Bytes(), want) {
+			t.Errorf("written record is not as expected, got: %x, want: %x", buffer.Bytes(), want)
+		}
+	})
+	t.Run("write record with too long id fails", func(t *testing.T) {
+		if _, err := WriteVerifyRecord(io.Discard, strings.Repeat("a", math.MaxUint16+1), derived); err == nil {
+			t.Error("writing a record with a too long id should fail")
+		}
+	})
+	t.Run("write record with invalid hash fails", func(t *testing.T) {
+		_, err := WriteVerifyRecord(io.Discard, "alice", derived[:len(derived)-1])
+		if !errors.Is(err, ErrInvalidHashLength) {
+			t.Errorf("writing a record with an invalid hash should fail with invalid hash length, got: %v", err)
+		}
+	})
+	t.Run("write record to failing writer", func(t *testing.T) {
+		if _, err := WriteVerifyRecord(failWriter{}, "alice", derived); err == nil {
+			t.Error("writing a record to a failing writer should fail")
+		}
+	})
+}
+
+func TestVerifyStream(t *testing.T) {
+	passwords := map[string]string{"alice": "alice-password", "bob": "bob-password", "carol": "carol-password"}
+	lookup := func(id string) (string, bool) {
+		password, ok := passwords[id]
+		return password, ok
+	}
+	stream := bytes.NewBuffer(nil)
+	for _, record := range []struct{ id, password string }{
+		{"alice", "alice-password"},
+		{"bob", "wrong-password"},
+		{"dave", "dave-password"},
+		{"carol", "carol-password"},
+	} {
+		hash, err := Derive(record.password, testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		if _, err = WriteVerifyRecord(stream, record.id, hash); err != nil {
+			t.Fatalf("failed to write verify record: %s", err)
+		}
+	}
+	records := stream.Bytes()
+
+	t.Run("verify stream", func(t *testing.T) {
+		report, err := VerifyStream(bytes.NewReader(records), lookup)
+		if err != nil {
+			t.Fatalf("failed to verify stream: %s", err)
+		}
+		want := VerifyReport{Total: 4, Passed: 2, Failed: 1, Skipped: 1, FailedIDs: []string{"bob"}}
+		if !reflect.DeepEqual(report, want) {
+			t.Errorf("verify report is not as expected, got: %+v, want: %+v", report, want)
+		}
+	})
+	t.Run("verify empty stream", func(t *testing.T) {
+		report, err := VerifyStream(bytes.NewReader(nil), lookup)
+		if err != nil {
+			t.Fatalf("failed to verify empty stream: %s", err)
+		}
+		if !reflect.DeepEqual(report, VerifyReport{}) {
+			t.Errorf("verify report of empty stream should be empty, got: %+v", report)
+		}
+	})
+	t.Run("verify truncated stream fails", func(t *testing.T) {
+		recordLength := verifyIDLengthPrefixSize + len("alice") + testFastSettings.HashLength()
+		for _, length := range []int{1, recordLength + 3, len(records) - 1} {
+			report, err := VerifyStream(bytes.NewReader(records[:length]), lookup)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("verification of stream truncated to %d bytes should fail with unexpected EOF, got: %v",
+					length, err)
+			}
+			if want := length / recordLength; report.Total != want {
+				t.Errorf("verify report of truncated stream is not as expected, got: %d records, want: %d",
+					report.Total, want)
+			}
+		}
+	})
+	t.Run("verify stream with invalid header fails", func(t *testing.T) {
+		corrupted := bytes.Clone(records)
+		corrupted[verifyIDLengthPrefixSize+len("alice")] = 0xff
+		if _, err := VerifyStream(bytes.NewReader(corrupted), lookup); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("verification of stream with invalid header should fail with invalid hash, got: %v", err)
+		}
+	})
+	t.Run("verify stream with corrupted key", func(t *testing.T) {
+		corrupted := bytes.Clone(records)
+		corrupted[verifyIDLengthPrefixSize+len("alice")+testFastSettings.HashLength()-1] ^= 0x01
+		report, err := VerifyStream(bytes.NewReader(corrupted), lookup)
+		if err != nil {
+			t.Fatalf("failed to verify stream: %s", err)
+		}
+		if want := []string{"alice", "bob"}; !reflect.DeepEqual(report.FailedIDs, want) {
+			t.Errorf("failed ids are not as expected, got: %v, want: %v", report.FailedIDs, want)
+		}
+	})
+	t.Run("verify stream caps failed ids", func(t *testing.T) {
+		hash, err := Derive("wrong-password", testFastSettings)
+		if err != nil {
+			t.Fatalf("failed to derive hash: %s", err)
+		}
+		failing := bytes.NewBuffer(nil)
+		for i := range MaxVerifyFailedIDs + 2 {
+			if _, err = WriteVerifyRecord(failing, fmt.Sprintf("user-%d", i), hash); err != nil {
+				t.Fatalf("failed to write verify record: %s", err)
+			}
+		}
+		report, err := VerifyStream(failing, func(string) (string, bool) { return testPassPhrase, true })
+		if err != nil {
+			t.Fatalf("failed to verify stream: %s", err)
+		}
+		if report.Failed != MaxVerifyFailedIDs+2 {
+			t.Errorf("unexpected number of failed records, got: %d, want: %d", report.Failed, MaxVerifyFailedIDs+2)
+		}
+		if len(report.FailedIDs) != MaxVerifyFailedIDs || report.FailedIDs[0] != "user-0" {
+			t.Errorf("failed ids should be capped at %d, got: %d", MaxVerifyFailedIDs, len(report.FailedIDs))
+		}
+	})
+}